@@ -9,13 +9,16 @@ import (
 	"net/http"
 	"net/http/pprof"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/expfmt"
 	"k8s.io/klog/v2"
 
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
@@ -49,8 +52,24 @@ func main() {
 		opts.Usage()
 		os.Exit(0)
 	}
-	collectorBuilder := ocollectors.NewBuilder(context.TODO())
+	// The collectors stop on SIGTERM, letting their background tasks, such as
+	// the last save of the findings state, finish before exiting.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	collectorBuilder := ocollectors.NewBuilder(ctx)
 	collectorBuilder.WithApiserver(opts.Apiserver).WithKubeConfig(opts.Kubeconfig)
+	collectorBuilder.WithLocalClusterName(opts.LocalClusterName)
+	collectorBuilder.WithClusterIDSources(opts.ClusterIDSources)
+	collectorBuilder.WithManagedClusterLabelsAllowlist(opts.ManagedClusterLabelsAllowlist)
+	collectorBuilder.WithMaxReportAge(opts.MaxReportAge)
+	collectorBuilder.WithMaxResultResources(opts.MaxResultResources)
+	collectorBuilder.WithMissingReportGracePeriod(opts.MissingReportGracePeriod)
+	collectorBuilder.WithPolicyReportAPIVersion(opts.PolicyReportAPIVersion)
+	collectorBuilder.WithPolicyReportSelectors(opts.PolicyReportLabelSelector, opts.PolicyReportFieldSelector)
+	collectorBuilder.WithPolicyReportSources(opts.PolicyReportSources)
+	collectorBuilder.WithSeverityMapping(opts.SeverityMappingFile, opts.SeverityMappingConfigMap)
+	collectorBuilder.WithRuleContent(opts.RuleContentFile, opts.RuleContentConfigMap)
+	collectorBuilder.WithFindingStateFile(opts.FindingStateFile)
 	if len(opts.Collectors) == 0 {
 		klog.Info("Using default collectors")
 		collectorBuilder.WithEnabledCollectors(options.DefaultCollectors.AsSlice())
@@ -75,8 +94,14 @@ func main() {
 		klog.Fatal(err)
 	}
 
+	if err := whiteBlackList.Parse(); err != nil {
+		klog.Fatalf("error initializing the whiteblack list: %v", err)
+	}
+
 	klog.Infof("metric white- blacklisting: %v", whiteBlackList.Status())
 
+	collectorBuilder.WithNamespaceDenylist(opts.NamespaceDenylist)
+	collectorBuilder.WithOnlyManagedClusterNamespaces(opts.OnlyManagedClusterNamespaces)
 	collectorBuilder.WithWhiteBlackList(whiteBlackList)
 
 	ocmMetricsRegistry := prometheus.NewRegistry()
@@ -86,6 +111,9 @@ func main() {
 	if err := ocmMetricsRegistry.Register(ocollectors.ScrapeErrorTotalMetric); err != nil {
 		panic(err)
 	}
+	if err := ocmMetricsRegistry.Register(ocollectors.CollectorReadyMetric); err != nil {
+		panic(err)
+	}
 	if err := ocmMetricsRegistry.Register(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{})); err != nil {
 		panic(err)
 	}
@@ -95,8 +123,13 @@ func main() {
 	go telemetryServer(ocmMetricsRegistry, opts.TelemetryHost, opts.TelemetryPort, opts.TLSCrtFile, opts.TLSKeyFile)
 
 	collectors := collectorBuilder.Build()
+	gatherer := collectorBuilder.BuildGatherer()
+
+	go serveMetrics(collectors, gatherer, opts.Host, opts.Port, opts.EnableGZIPEncoding, opts.TLSCrtFile, opts.TLSKeyFile)
 
-	serveMetrics(collectors, opts.Host, opts.Port, opts.EnableGZIPEncoding, opts.TLSCrtFile, opts.TLSKeyFile)
+	<-ctx.Done()
+	klog.Info("Shutting down")
+	collectorBuilder.Wait()
 }
 func telemetryServer(registry prometheus.Gatherer, host string, port int, tlsCrtFile string, tlsKeyFile string) {
 	// Address to listen on for web interface and telemetry
@@ -139,7 +172,7 @@ func telemetryServer(registry prometheus.Gatherer, host string, port int, tlsCrt
 }
 
 // TODO: How about accepting an interface Collector instead?
-func serveMetrics(collectors []*metricsstore.MetricsStore, host string, port int, enableGZIPEncoding bool, tlsCrtFile string,
+func serveMetrics(collectors []*metricsstore.MetricsStore, gatherer prometheus.Gatherer, host string, port int, enableGZIPEncoding bool, tlsCrtFile string,
 	tlsKeyFile string) {
 	// Address to listen on for web interface and telemetry
 	listenAddress := net.JoinHostPort(host, strconv.Itoa(port))
@@ -156,7 +189,7 @@ func serveMetrics(collectors []*metricsstore.MetricsStore, host string, port int
 	mux.Handle("/debug/pprof/trace", http.HandlerFunc(pprof.Trace))
 
 	// Add metricsPath
-	mux.Handle(metricsPath, &metricHandler{collectors, enableGZIPEncoding})
+	mux.Handle(metricsPath, &metricHandler{collectors, gatherer, enableGZIPEncoding})
 	// Add healthzPath
 	mux.HandleFunc(healthzPath, func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(200)
@@ -195,7 +228,10 @@ func serveMetrics(collectors []*metricsstore.MetricsStore, host string, port int
 }
 
 type metricHandler struct {
-	collectors         []*metricsstore.MetricsStore
+	collectors []*metricsstore.MetricsStore
+	// gatherer holds the metrics of the collectors not generated from
+	// objects, written after those of the collectors.
+	gatherer           prometheus.Gatherer
 	enableGZIPEncoding bool
 }
 
@@ -222,6 +258,16 @@ func (m *metricHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	for _, c := range m.collectors {
 		c.WriteAll(w)
 	}
+	families, err := m.gatherer.Gather()
+	if err != nil {
+		klog.Warningf("Error gathering metrics: %v", err)
+	}
+	encoder := expfmt.NewEncoder(w, expfmt.NewFormat(expfmt.TypeTextPlain))
+	for _, f := range families {
+		if err := encoder.Encode(f); err != nil {
+			klog.Warningf("Error writing metric family %s: %v", f.GetName(), err)
+		}
+	}
 
 	// In case we gziped the response, we have to close the writer.
 	if closer, ok := writer.(io.Closer); ok {
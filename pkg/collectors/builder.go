@@ -3,13 +3,20 @@ package collectors
 import (
 	"sort"
 	"strings"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/kube-state-metrics/pkg/metric"
 	"k8s.io/kube-state-metrics/pkg/options"
 
 	"golang.org/x/net/context"
 	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/klog/v2"
@@ -24,12 +31,41 @@ type whiteBlackLister interface {
 // Builder helps to build collectors. It follows the builder pattern
 // (https://en.wikipedia.org/wiki/Builder_pattern).
 type Builder struct {
-	apiserver         string
-	kubeconfig        string
-	namespaces        options.NamespaceList
-	ctx               context.Context
-	enabledCollectors []string
-	whiteBlackList    whiteBlackLister
+	apiserver                string
+	kubeconfig               string
+	namespaces               options.NamespaceList
+	namespaceDenylist        []string
+	onlyClusterNamespaces    bool
+	ctx                      context.Context
+	enabledCollectors        []string
+	whiteBlackList           whiteBlackLister
+	localClusterName         string
+	clusterIDSources         []string
+	clusterLabels            []string
+	maxReportAge             time.Duration
+	maxResultResources       int
+	missingReportGracePeriod time.Duration
+	policyReportAPIVersion   string
+	reportLabelSelector      string
+	reportFieldSelector      string
+	reportSources            []string
+	severityMappingFile      string
+	severityMappingConfigMap string
+	ruleContentFile          string
+	ruleContentConfigMap     string
+	findingStateFile         string
+	// severities is the severity mapping shared by the collectors, loaded on
+	// first use.
+	severities *severityMapping
+	// clusters is the cluster cache shared by the collectors, created on
+	// first use.
+	clusters *clusterCache
+	// crds gates the reflectors of the collectors on their CRDs, created on
+	// first use.
+	crds *crdWatcher
+	// tasks runs the background tasks to let finish once the context is
+	// done, such as the last save of the findings state.
+	tasks wait.Group
 }
 
 // NewBuilder returns a new builder.
@@ -69,6 +105,22 @@ func (b *Builder) WithNamespaces(n options.NamespaceList) *Builder {
 	return b
 }
 
+// WithNamespaceDenylist sets the namespaces never collected, even when selected
+// by WithNamespaces. Entries that are not valid namespace names are regular
+// expressions matching whole namespace names.
+func (b *Builder) WithNamespaceDenylist(n []string) *Builder {
+	b.namespaceDenylist = n
+	return b
+}
+
+// WithOnlyManagedClusterNamespaces restricts the collected namespaces to those
+// backing a ManagedCluster, following the ManagedClusters as they are imported
+// or detached.
+func (b *Builder) WithOnlyManagedClusterNamespaces(only bool) *Builder {
+	b.onlyClusterNamespaces = only
+	return b
+}
+
 // WithWhiteBlackList configures the white or blacklisted metrics to be exposed
 // by the collectors build by the Builder
 func (b *Builder) WithWhiteBlackList(l whiteBlackLister) *Builder {
@@ -76,6 +128,100 @@ func (b *Builder) WithWhiteBlackList(l whiteBlackLister) *Builder {
 	return b
 }
 
+// WithLocalClusterName sets the name of the ManagedCluster representing the hub.
+// When empty, the hub is the ManagedCluster labelled local-cluster=true.
+func (b *Builder) WithLocalClusterName(name string) *Builder {
+	b.localClusterName = name
+	return b
+}
+
+// WithClusterIDSources sets the order in which the sources of a cluster ID are
+// tried: ClusterClaim names, "uid" or "name".
+func (b *Builder) WithClusterIDSources(sources []string) *Builder {
+	b.clusterIDSources = sources
+	return b
+}
+
+// WithManagedClusterLabelsAllowlist sets the ManagedCluster labels added to the
+// PolicyReport metrics.
+func (b *Builder) WithManagedClusterLabelsAllowlist(labels []string) *Builder {
+	b.clusterLabels = labels
+	return b
+}
+
+// WithMaxReportAge sets the age after which a PolicyReport is flagged as stale.
+// Zero disables the policyreport_stale metric.
+func (b *Builder) WithMaxReportAge(age time.Duration) *Builder {
+	b.maxReportAge = age
+	return b
+}
+
+// WithMaxResultResources sets the maximum number of policyreport_result_resources
+// series per managed cluster. Zero disables policyreport_result_resources.
+func (b *Builder) WithMaxResultResources(limit int) *Builder {
+	b.maxResultResources = limit
+	return b
+}
+
+// WithMissingReportGracePeriod sets how long an available ManagedCluster can go
+// without a PolicyReport before it is flagged by policyreport_missing. Zero
+// disables the policyreport_missing metric.
+func (b *Builder) WithMissingReportGracePeriod(gracePeriod time.Duration) *Builder {
+	b.missingReportGracePeriod = gracePeriod
+	return b
+}
+
+// WithPolicyReportAPIVersion forces the group/version of the PolicyReport API,
+// such as wgpolicyk8s.io/v1alpha2. When empty, the collectors read every API
+// group whose CRD is established, wgpolicyk8s.io and openreports.io, each on
+// its most preferred established version, switching to a more preferred
+// version of the group when its CRD starts serving it.
+func (b *Builder) WithPolicyReportAPIVersion(version string) *Builder {
+	b.policyReportAPIVersion = version
+	return b
+}
+
+// WithPolicyReportSelectors sets the label and field selectors restricting the
+// PolicyReports and ClusterPolicyReports listed and watched.
+func (b *Builder) WithPolicyReportSelectors(label string, field string) *Builder {
+	b.reportLabelSelector = label
+	b.reportFieldSelector = field
+	return b
+}
+
+// WithPolicyReportSources sets the sources, such as insights or kyverno, whose
+// PolicyReport results are collected. Every source is collected when empty.
+func (b *Builder) WithPolicyReportSources(sources []string) *Builder {
+	b.reportSources = sources
+	return b
+}
+
+// WithSeverityMapping sets where the mapping of PolicyReport results to their
+// severity is read from: a file, or a ConfigMap given as namespace/name. The
+// Insights total_risk mapping is used when both are empty.
+func (b *Builder) WithSeverityMapping(file string, configMap string) *Builder {
+	b.severityMappingFile = file
+	b.severityMappingConfigMap = configMap
+	return b
+}
+
+// WithRuleContent sets where the rules bundle exposed by policyreport_rule_info
+// is read from: a file, or a ConfigMap given as namespace/name. It is reloaded
+// when it changes. policyreport_rule_info is disabled when both are empty.
+func (b *Builder) WithRuleContent(file string, configMap string) *Builder {
+	b.ruleContentFile = file
+	b.ruleContentConfigMap = configMap
+	return b
+}
+
+// WithFindingStateFile sets the file the first-seen times of the findings are
+// persisted to, so that they survive restarts. They are kept in memory only
+// when empty.
+func (b *Builder) WithFindingStateFile(file string) *Builder {
+	b.findingStateFile = file
+	return b
+}
+
 // Build initializes and registers all enabled collectors.
 func (b *Builder) Build() []*metricsstore.MetricsStore {
 	if b.whiteBlackList == nil {
@@ -93,7 +239,7 @@ func (b *Builder) Build() []*metricsstore.MetricsStore {
 
 		collector := constructor(b)
 		activeCollectorNames = append(activeCollectorNames, c)
-		collectors = append(collectors, collector)
+		collectors = append(collectors, collector...)
 
 	}
 
@@ -102,22 +248,285 @@ func (b *Builder) Build() []*metricsstore.MetricsStore {
 	return collectors
 }
 
-var availableCollectors = map[string]func(f *Builder) *metricsstore.MetricsStore{
-	"policyreports": func(b *Builder) *metricsstore.MetricsStore { return b.buildPolicyReportCollector() },
+// Wait blocks until the background tasks of the collectors, such as the last
+// save of the findings state, returned after the context of the Builder is
+// done.
+func (b *Builder) Wait() {
+	b.tasks.Wait()
+}
+
+// BuildGatherer returns the metrics of the enabled collectors that are not
+// generated from objects, such as the counters of the findings, filtered by the
+// whitelist and blacklist. They are served along with the metrics of Build.
+func (b *Builder) BuildGatherer() prometheus.Gatherer {
+	if b.whiteBlackList == nil {
+		panic("whiteBlackList should not be nil")
+	}
+
+	gatherers := prometheus.Gatherers{}
+	for _, c := range b.enabledCollectors {
+		if c == "policyreports" {
+			gatherers = append(gatherers, findingsRegistry)
+		}
+	}
+	return &filteredGatherer{Gatherer: gatherers, whiteBlackList: b.whiteBlackList}
+}
+
+// filteredGatherer gathers the families of its Gatherer included by the
+// whitelist and blacklist.
+type filteredGatherer struct {
+	prometheus.Gatherer
+	whiteBlackList whiteBlackLister
+}
+
+func (g *filteredGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := g.Gatherer.Gather()
+	filtered := []*dto.MetricFamily{}
+	for _, f := range families {
+		if g.whiteBlackList.IsIncluded(f.GetName()) {
+			filtered = append(filtered, f)
+		}
+	}
+	return filtered, err
+}
+
+var availableCollectors = map[string]func(f *Builder) []*metricsstore.MetricsStore{
+	"policyreports":        func(b *Builder) []*metricsstore.MetricsStore { return b.buildPolicyReportCollector() },
+	"clusterpolicyreports": func(b *Builder) []*metricsstore.MetricsStore { return b.buildClusterPolicyReportCollector() },
+}
+
+func (b *Builder) buildPolicyReportCollector() []*metricsstore.MetricsStore {
+	return b.buildPolicyReportCollectorWithClient(dynamic.NewForConfigOrDie(b.restConfig()))
+}
+
+func (b *Builder) buildClusterPolicyReportCollector() []*metricsstore.MetricsStore {
+	return b.buildClusterPolicyReportCollectorWithClient(dynamic.NewForConfigOrDie(b.restConfig()))
 }
 
-func (b *Builder) buildPolicyReportCollector() *metricsstore.MetricsStore {
+func (b *Builder) restConfig() *rest.Config {
 	config, err := clientcmd.BuildConfigFromFlags(b.apiserver, b.kubeconfig)
 	if err != nil {
 		klog.Fatalf("cannot create Dynamic client: %v", err)
 	}
-	client := dynamic.NewForConfigOrDie(config)
-	return b.buildPolicyReportCollectorWithClient(client)
+	return config
+}
+
+// reportAPICandidates returns the PolicyReport API versions the collectors can
+// read, most preferred first within each group: the forced version only, or
+// every supported one.
+func (b *Builder) reportAPICandidates() []reportAPI {
+	if b.policyReportAPIVersion == "" {
+		return reportAPIs
+	}
+	api, err := reportAPIByVersion(b.policyReportAPIVersion)
+	if err != nil {
+		klog.Fatalf("cannot use PolicyReport API: %v", err)
+	}
+	return []reportAPI{api}
+}
+
+// runWhileReportAPIEstablished runs the reflectors of the collector on each
+// group of the PolicyReport API candidates, on the most preferred version of
+// the group whose CRD is established. The groups run side by side into the
+// given store, each dropping only its own reports when it stops, and their
+// readiness is exposed under the collector name followed by the group, such as
+// policyreports/wgpolicyk8s.io.
+func (b *Builder) runWhileReportAPIEstablished(
+	client dynamic.Interface,
+	collector string,
+	resource func(reportAPI) schema.GroupVersionResource,
+	run func(ctx context.Context, api reportAPI, store *reportGroupStore),
+	store *policyReportStore,
+) {
+	for _, apis := range reportAPIGroups(b.reportAPICandidates()) {
+		group := apis[0].groupVersion.Group
+		groupStore := &reportGroupStore{policyReportStore: store, group: group}
+		gvrs := []schema.GroupVersionResource{}
+		for _, api := range apis {
+			gvrs = append(gvrs, resource(api))
+		}
+		b.crdWatcherWithClient(client).runWhileAnyEstablished(collector+"/"+group, gvrs, func(ctx context.Context, gvr schema.GroupVersionResource) {
+			run(ctx, reportAPIOfGroupVersion(gvr.GroupVersion()), groupStore)
+		}, func() {
+			// The reports are gone along with their CRD, not resolved.
+			_ = groupStore.Drop()
+		})
+	}
+}
+
+// clusterCacheWithClient returns the cluster cache shared by the collectors,
+// creating and syncing it with the given client on first use.
+func (b *Builder) clusterCacheWithClient(client dynamic.Interface) *clusterCache {
+	if b.clusters == nil {
+		b.clusters = newClusterCache(client, b.localClusterName, b.clusterIDSources)
+		if !b.clusters.start(b.ctx) {
+			klog.Fatal("cannot sync ManagedCluster informer")
+		}
+	}
+	return b.clusters
 }
 
-func (b *Builder) buildPolicyReportCollectorWithClient(client dynamic.Interface) *metricsstore.MetricsStore {
+// namespaceFilter returns the selection of the namespaces to collect, shared by
+// every collector.
+func (b *Builder) namespaceFilter() namespaceFilter {
+	filter, err := newNamespaceFilter(b.namespaces, b.namespaceDenylist)
+	if err != nil {
+		klog.Fatalf("cannot select namespaces: %v", err)
+	}
+	return filter
+}
+
+// reportSelectors returns the selectors of the reports, shared by every
+// collector.
+func (b *Builder) reportSelectors() reportSelectors {
+	selectors, err := newReportSelectors(b.reportLabelSelector, b.reportFieldSelector)
+	if err != nil {
+		klog.Fatalf("cannot select PolicyReports: %v", err)
+	}
+	return selectors
+}
+
+// resultOptionsWithClient returns how the collectors turn report results into
+// metrics, loading the severity mapping with the given client on first use.
+func (b *Builder) resultOptionsWithClient(client dynamic.Interface) resultOptions {
+	if b.severities == nil {
+		severities, err := loadSeverityMapping(client, b.severityMappingFile, b.severityMappingConfigMap)
+		if err != nil {
+			klog.Fatalf("cannot load severity mapping: %v", err)
+		}
+		b.severities = &severities
+	}
+	return newResultOptions(b.reportSources, b.severities)
+}
+
+// crdWatcherWithClient returns the CRD watcher shared by the collectors,
+// creating it with the given client on first use.
+func (b *Builder) crdWatcherWithClient(client dynamic.Interface) *crdWatcher {
+	if b.crds == nil {
+		b.crds = newCRDWatcher(b.ctx, client)
+	}
+	return b.crds
+}
+
+func (b *Builder) buildPolicyReportCollectorWithClient(client dynamic.Interface) []*metricsstore.MetricsStore {
+	clusters := b.clusterCacheWithClient(client)
+	results := b.resultOptionsWithClient(client)
+	findings, err := newFindingTracker(results, b.findingStateFile)
+	if err != nil {
+		klog.Fatalf("cannot load findings state: %v", err)
+	}
+	filter := b.namespaceFilter()
+	findings.watched = filter.matches
+	if b.onlyClusterNamespaces {
+		findings.watched = func(namespace string) bool {
+			return filter.matches(namespace) && clusters.hasCluster(namespace)
+		}
+	}
+	findings.discard()
+	b.tasks.Start(func() {
+		findings.saveEvery(b.ctx, findingStateSavePeriod)
+	})
+
+	opts := policyReportOptions{
+		clusterLabels:      b.clusterLabels,
+		maxReportAge:       b.maxReportAge,
+		maxResultResources: b.maxResultResources,
+		results:            results,
+		findings:           findings,
+	}
+	filteredMetricFamilies := metric.FilterMetricFamilies(b.whiteBlackList, getPolicyReportMetricFamilies(clusters, opts))
+	composedMetricGenFuncs := metric.ComposeMetricGenFuncs(filteredMetricFamilies)
+
+	familyHeaders := metric.ExtractMetricFamilyHeaders(filteredMetricFamilies)
+
+	store := metricsstore.NewMetricsStore(
+		familyHeaders,
+		composedMetricGenFuncs,
+	)
+	clusterFamilies := metric.FilterMetricFamilies(b.whiteBlackList, getClusterReportMetricFamilies(clusters, opts))
+	clusterStore := metricsstore.NewMetricsStore(
+		metric.ExtractMetricFamilyHeaders(clusterFamilies),
+		metric.ComposeMetricGenFuncs(clusterFamilies),
+	)
+	prStore := newPolicyReportStore(store, clusters)
+	prStore.perCluster = clusterStore
+	prStore.findings = findings
+	if b.maxReportAge > 0 {
+		// Staleness moves on with time alone, regenerate the metrics often
+		// enough to flag a report shortly after it goes stale.
+		go prStore.resyncEvery(b.ctx, max(b.maxReportAge/10, time.Minute))
+	}
+	selectors := b.reportSelectors()
+	b.runWhileReportAPIEstablished(client, "policyreports", reportAPI.gvr, func(ctx context.Context, api reportAPI, reports *reportGroupStore) {
+		klog.Infof("PolicyReport API version: %s", api)
+		if b.onlyClusterNamespaces {
+			newClusterNamespaceReflectors(clusters, b.namespaceFilter(), reports, &unstructured.Unstructured{},
+				func(ns string) cache.ListWatch {
+					return createPolicyReportListWatch(b.apiserver, b.kubeconfig, api, selectors, ns)
+				}).run(ctx)
+			return
+		}
+		runNamespacedReflectors(ctx, &unstructured.Unstructured{}, reports,
+			b.apiserver, b.kubeconfig, b.namespaceFilter(), func(apiserver string, kubeconfig string, ns string) cache.ListWatch {
+				return createPolicyReportListWatch(apiserver, kubeconfig, api, selectors, ns)
+			})
+	}, prStore)
+
+	stores := []*metricsstore.MetricsStore{store, clusterStore}
+	if b.missingReportGracePeriod > 0 {
+		stores = append(stores, b.buildMissingReportCollector(clusters, prStore))
+	}
+	if b.ruleContentFile != "" || b.ruleContentConfigMap != "" {
+		stores = append(stores, b.buildRuleContentCollector(client))
+	}
+
+	return stores
+}
+
+func (b *Builder) buildClusterPolicyReportCollectorWithClient(client dynamic.Interface) []*metricsstore.MetricsStore {
+	clusters := b.clusterCacheWithClient(client)
+
 	filteredMetricFamilies := metric.FilterMetricFamilies(b.whiteBlackList,
-		getPolicyReportMetricFamilies(client))
+		getClusterPolicyReportMetricFamilies(clusters, b.resultOptionsWithClient(client)))
+	composedMetricGenFuncs := metric.ComposeMetricGenFuncs(filteredMetricFamilies)
+
+	familyHeaders := metric.ExtractMetricFamilyHeaders(filteredMetricFamilies)
+
+	store := metricsstore.NewMetricsStore(
+		familyHeaders,
+		composedMetricGenFuncs,
+	)
+	// ClusterPolicyReports are tracked under the hub, so that their metrics
+	// are regenerated when the hub's identity changes.
+	cprStore := newPolicyReportStore(store, clusters)
+	selectors := b.reportSelectors()
+	b.runWhileReportAPIEstablished(client, "clusterpolicyreports", reportAPI.clusterGVR, func(ctx context.Context, api reportAPI, reports *reportGroupStore) {
+		lw := createClusterPolicyReportListWatchWithClient(client, api, selectors)
+		reflector := cache.NewReflector(&lw, &unstructured.Unstructured{}, reports, 0)
+		reflector.Run(ctx.Done())
+	}, cprStore)
+
+	return []*metricsstore.MetricsStore{store}
+}
+
+func (b *Builder) buildMissingReportCollector(clusters *clusterCache, reports *policyReportStore) *metricsstore.MetricsStore {
+	filteredMetricFamilies := metric.FilterMetricFamilies(b.whiteBlackList,
+		getMissingReportMetricFamilies(clusters, reports, b.namespaceFilter(), b.missingReportGracePeriod, now()))
+	composedMetricGenFuncs := metric.ComposeMetricGenFuncs(filteredMetricFamilies)
+
+	familyHeaders := metric.ExtractMetricFamilyHeaders(filteredMetricFamilies)
+
+	store := metricsstore.NewMetricsStore(
+		familyHeaders,
+		composedMetricGenFuncs,
+	)
+	feedManagedClusters(b.ctx, clusters, store, missingReportResyncPeriod)
+
+	return store
+}
+
+func (b *Builder) buildRuleContentCollector(client dynamic.Interface) *metricsstore.MetricsStore {
+	filteredMetricFamilies := metric.FilterMetricFamilies(b.whiteBlackList, getRuleInfoMetricFamilies())
 	composedMetricGenFuncs := metric.ComposeMetricGenFuncs(filteredMetricFamilies)
 
 	familyHeaders := metric.ExtractMetricFamilyHeaders(filteredMetricFamilies)
@@ -126,14 +535,49 @@ func (b *Builder) buildPolicyReportCollectorWithClient(client dynamic.Interface)
 		familyHeaders,
 		composedMetricGenFuncs,
 	)
-	reflectorPerNamespace(b.ctx, &unstructured.Unstructured{}, store,
-		b.apiserver, b.kubeconfig, b.namespaces, createPolicyReportListWatch)
+	loader := &ruleContentLoader{
+		client:    client,
+		file:      b.ruleContentFile,
+		configMap: b.ruleContentConfigMap,
+		store:     store,
+	}
+	if err := loader.load(); err != nil {
+		klog.Fatalf("cannot load rule content: %v", err)
+	}
+	go loader.run(b.ctx, ruleContentReloadPeriod)
 
 	return store
 }
 
+// runNamespacedReflectors runs the reflectors feeding the store with the
+// objects of the namespaces selected by the filter: one reflector per namespace
+// for a short list of namespaces, a single cluster-wide reflector filtering
+// namespaces in process otherwise. It blocks until the context is done and
+// every reflector returned.
+func runNamespacedReflectors(
+	ctx context.Context,
+	expectedType interface{},
+	store cache.Store,
+	apiserver string,
+	kubeconfig string,
+	filter namespaceFilter,
+	listWatchFunc func(apiserver string, kubeconfig string, ns string) cache.ListWatch,
+) {
+	if namespaces, ok := filter.perNamespace(); ok {
+		klog.Infof("Watching %d namespaces with a reflector each", len(namespaces))
+		reflectorPerNamespace(ctx, expectedType, store, apiserver, kubeconfig, namespaces, listWatchFunc)
+		return
+	}
+	klog.Info("Watching all namespaces with a single reflector")
+	reflectorPerNamespace(ctx, expectedType, &filteredStore{Store: store, matches: filter.matches},
+		apiserver, kubeconfig, []string{metav1.NamespaceAll}, listWatchFunc)
+}
+
 // reflectorPerNamespace creates a Kubernetes client-go reflector with the given
 // listWatchFunc for each given namespace and registers it with the given store.
+// A store that can be replaced one namespace at a time is only replaced in the
+// namespace of each reflector. It blocks until the context is done and every
+// reflector returned.
 func reflectorPerNamespace(
 	ctx context.Context,
 	expectedType interface{},
@@ -143,9 +587,15 @@ func reflectorPerNamespace(
 	namespaces []string,
 	listWatchFunc func(apiserver string, kubeconfig string, ns string) cache.ListWatch,
 ) {
+	var wg wait.Group
 	for _, ns := range namespaces {
 		lw := listWatchFunc(apiserver, kubeconfig, ns)
-		reflector := cache.NewReflector(&lw, expectedType, store, 0)
-		go reflector.Run(ctx.Done())
+		nsStore := store
+		if replacer, ok := store.(namespaceReplacer); ok && ns != metav1.NamespaceAll {
+			nsStore = &namespaceStore{namespaceReplacer: replacer, namespace: ns}
+		}
+		reflector := cache.NewReflector(&lw, expectedType, nsStore, 0)
+		wg.StartWithChannel(ctx.Done(), reflector.Run)
 	}
+	wg.Wait()
 }
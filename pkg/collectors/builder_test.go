@@ -298,3 +298,97 @@ func TestBuilder_WithWhiteBlackList(t *testing.T) {
 		})
 	}
 }
+
+func TestBuilder_WithLocalClusterName(t *testing.T) {
+	type fields struct {
+		namespaces        koptions.NamespaceList
+		ctx               context.Context
+		enabledCollectors []string
+		localClusterName  string
+	}
+	type args struct {
+		name string
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		args   args
+		want   *Builder
+	}{
+		{
+			name: "localClusterName",
+			fields: fields{
+				namespaces:        koptions.NamespaceList{},
+				ctx:               ctx,
+				enabledCollectors: []string{"col1", "col2"},
+				localClusterName:  "",
+			},
+			args: args{
+				name: "hub",
+			},
+			want: &Builder{
+				namespaces:        koptions.NamespaceList{},
+				ctx:               ctx,
+				enabledCollectors: []string{"col1", "col2"},
+				localClusterName:  "hub",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Builder{
+				namespaces:        tt.fields.namespaces,
+				ctx:               tt.fields.ctx,
+				enabledCollectors: tt.fields.enabledCollectors,
+				localClusterName:  tt.fields.localClusterName,
+			}
+			if got := b.WithLocalClusterName(tt.args.name); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Builder.WithLocalClusterName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuilder_BuildGatherer(t *testing.T) {
+	l, err := whiteblacklist.New(map[string]struct{}{}, map[string]struct{}{"policyreport_findings_resolved_total": {}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	findingsRaisedTotalMetric.WithLabelValues("critical", "security").Add(0)
+	findingsResolvedTotalMetric.WithLabelValues("critical", "security").Add(0)
+
+	tests := []struct {
+		name       string
+		collectors []string
+		want       []string
+	}{
+		{
+			name:       "policyreports",
+			collectors: []string{"policyreports"},
+			want:       []string{"policyreport_finding_resolution_seconds", "policyreport_findings_raised_total"},
+		}, {
+			name:       "clusterpolicyreports only",
+			collectors: []string{"clusterpolicyreports"},
+			want:       []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBuilder(ctx).WithEnabledCollectors(tt.collectors).WithWhiteBlackList(l)
+			families, err := b.BuildGatherer().Gather()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, f := range families {
+				got = append(got, f.GetName())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected families %v got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,337 @@
+// Copyright (c) 2026 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+
+	ocinfrav1 "github.com/openshift/api/config/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+const (
+	localClusterLabel  = "local-cluster"
+	clusterSetLabel    = "cluster.open-cluster-management.io/clusterset"
+	clusterVersionName = "version"
+
+	// hubClusterName stands for the hub itself, which is where cluster-scoped
+	// reports such as ClusterPolicyReports live.
+	hubClusterName = ""
+
+	// clusterIDSourceClusterVersion is reported when the hub's ID comes from
+	// its ClusterVersion.
+	clusterIDSourceClusterVersion = "clusterversion"
+	// clusterIDSourceUID resolves a cluster to its ManagedCluster UID.
+	clusterIDSourceUID = "uid"
+	// clusterIDSourceName resolves a cluster to its ManagedCluster name.
+	clusterIDSourceName = "name"
+)
+
+// DefaultClusterIDSources is the order in which the sources of a cluster ID are
+// tried. Any source other than uid and name is the name of a ClusterClaim.
+var DefaultClusterIDSources = []string{"id.openshift.io", "id.k8s.io", clusterIDSourceUID, clusterIDSourceName}
+
+// clusterInfo is what the cache remembers about a single ManagedCluster.
+type clusterInfo struct {
+	uid string
+	// claims maps the ClusterClaim names to their values.
+	claims map[string]string
+	// local is true when the ManagedCluster is labelled local-cluster=true.
+	local  bool
+	labels map[string]string
+}
+
+// clusterCache is an in-memory index of cluster identities. It is fed by shared
+// informers on ManagedClusters and ClusterVersions so that PolicyReport metric
+// generation never has to reach the API server to resolve a cluster ID.
+type clusterCache struct {
+	mu sync.RWMutex
+	// clusters maps a ManagedCluster name to what is known about it.
+	clusters map[string]clusterInfo
+	// hubID is the ClusterID of the hub's own ClusterVersion.
+	hubID string
+	// localClusterName names the hub's ManagedCluster. When empty the hub is
+	// the ManagedCluster labelled local-cluster=true.
+	localClusterName string
+	// idSources is the fallback order used to resolve a cluster ID.
+	idSources []string
+	// listeners are called with the cluster name whenever its identity or
+	// labels change.
+	listeners []func(clusterName string)
+
+	factory    dynamicinformer.DynamicSharedInformerFactory
+	mcInformer cache.SharedIndexInformer
+	synced     []cache.InformerSynced
+}
+
+// newClusterCache returns a clusterCache whose informers use the given client.
+// The informers are not running until start is called. An empty idSources uses
+// DefaultClusterIDSources.
+func newClusterCache(client dynamic.Interface, localClusterName string, idSources []string) *clusterCache {
+	if len(idSources) == 0 {
+		idSources = DefaultClusterIDSources
+	}
+	c := &clusterCache{
+		clusters:         map[string]clusterInfo{},
+		localClusterName: localClusterName,
+		idSources:        idSources,
+		factory:          dynamicinformer.NewDynamicSharedInformerFactory(client, 0),
+	}
+
+	mcInformer := c.factory.ForResource(mcGVR).Informer()
+	if _, err := mcInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.setManagedCluster,
+		UpdateFunc: func(_, obj interface{}) { c.setManagedCluster(obj) },
+		DeleteFunc: c.deleteManagedCluster,
+	}); err != nil {
+		klog.Fatalf("cannot watch ManagedClusters: %v", err)
+	}
+
+	cvInformer := c.factory.ForResource(cvGVR).Informer()
+	if _, err := cvInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.setClusterVersion,
+		UpdateFunc: func(_, obj interface{}) { c.setClusterVersion(obj) },
+		DeleteFunc: func(interface{}) { c.setHubID("") },
+	}); err != nil {
+		klog.Fatalf("cannot watch ClusterVersions: %v", err)
+	}
+
+	c.mcInformer = mcInformer
+	// ClusterVersions are only served by OpenShift hubs, the hub's ID is
+	// resolved whenever the ClusterVersion informer syncs, if ever.
+	c.synced = []cache.InformerSynced{mcInformer.HasSynced}
+	return c
+}
+
+// start runs the informers and blocks until the initial list of ManagedClusters
+// is indexed or the context is done.
+func (c *clusterCache) start(ctx context.Context) bool {
+	c.factory.Start(ctx.Done())
+	return cache.WaitForCacheSync(ctx.Done(), c.synced...)
+}
+
+// clusterID returns the ID of the cluster backing the given cluster namespace,
+// or "" if it is not known yet.
+func (c *clusterCache) clusterID(clusterName string) string {
+	id, _ := c.clusterIdentity(clusterName)
+	return id
+}
+
+// clusterIdentity returns the ID of the cluster backing the given cluster
+// namespace along with the source it was resolved from.
+func (c *clusterCache) clusterIdentity(clusterName string) (string, string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.clusterIdentityLocked(clusterName)
+}
+
+// clusterIdentityLocked resolves the hub to its ClusterVersion ID and every
+// cluster, including a hub without ClusterVersion, to the first non-empty
+// source in idSources.
+func (c *clusterCache) clusterIdentityLocked(clusterName string) (string, string) {
+	if (clusterName == hubClusterName || c.isLocalLocked(clusterName)) && c.hubID != "" {
+		return c.hubID, clusterIDSourceClusterVersion
+	}
+	if clusterName == hubClusterName {
+		clusterName = c.hubClusterLocked()
+	}
+	info, ok := c.clusters[clusterName]
+	if !ok {
+		return "", ""
+	}
+	for _, source := range c.idSources {
+		var id string
+		switch source {
+		case clusterIDSourceUID:
+			id = info.uid
+		case clusterIDSourceName:
+			id = clusterName
+		default:
+			id = info.claims[source]
+		}
+		if id != "" {
+			return id, source
+		}
+	}
+	return "", ""
+}
+
+// clusterLabels returns the labels of the ManagedCluster backing the given
+// cluster namespace. The returned map must not be modified.
+func (c *clusterCache) clusterLabels(clusterName string) map[string]string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.clusterLabelsLocked(clusterName)
+}
+
+func (c *clusterCache) clusterLabelsLocked(clusterName string) map[string]string {
+	if clusterName == hubClusterName {
+		clusterName = c.hubClusterLocked()
+	}
+	return c.clusters[clusterName].labels
+}
+
+// isLocalLocked reports whether the named cluster is the hub itself.
+func (c *clusterCache) isLocalLocked(clusterName string) bool {
+	if c.localClusterName != "" {
+		return clusterName == c.localClusterName
+	}
+	return c.clusters[clusterName].local
+}
+
+// localClustersLocked returns the names of the clusters resolved to the hub.
+func (c *clusterCache) localClustersLocked() []string {
+	if c.localClusterName != "" {
+		return []string{c.localClusterName}
+	}
+	names := []string{}
+	for name, info := range c.clusters {
+		if info.local {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// hubClusterLocked returns the name of the ManagedCluster representing the hub,
+// the first by name if several are labelled local-cluster=true, or "" if none.
+func (c *clusterCache) hubClusterLocked() string {
+	names := c.localClustersLocked()
+	if len(names) == 0 {
+		return ""
+	}
+	sort.Strings(names)
+	return names[0]
+}
+
+// hasCluster reports whether a ManagedCluster with the given name exists.
+func (c *clusterCache) hasCluster(clusterName string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, ok := c.clusters[clusterName]
+	return ok
+}
+
+// clusterNames returns the sorted names of the known ManagedClusters.
+func (c *clusterCache) clusterNames() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	names := make([]string, 0, len(c.clusters))
+	for name := range c.clusters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// onClusterChange registers f to be called with the cluster name whenever that
+// cluster is added or removed, or the ID resolved for it, its source or its
+// labels change. f is called with hubClusterName when the hub's own identity or
+// labels change.
+func (c *clusterCache) onClusterChange(f func(clusterName string)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.listeners = append(c.listeners, f)
+}
+
+// clusterView is what listeners can observe of a cluster.
+type clusterView struct {
+	exists   bool
+	id       string
+	idSource string
+	labels   map[string]string
+}
+
+func (c *clusterCache) viewLocked(clusterName string) clusterView {
+	id, source := c.clusterIdentityLocked(clusterName)
+	_, exists := c.clusters[clusterName]
+	return clusterView{exists: exists, id: id, idSource: source, labels: c.clusterLabelsLocked(clusterName)}
+}
+
+// update applies mutate under the cache lock, then notifies the listeners of
+// each cluster, among clusterName, the local clusters and the hub, that
+// changed.
+func (c *clusterCache) update(clusterName string, mutate func()) {
+	c.mu.Lock()
+	before := map[string]clusterView{}
+	for _, name := range append(c.localClustersLocked(), clusterName, hubClusterName) {
+		before[name] = c.viewLocked(name)
+	}
+	mutate()
+	changed := []string{}
+	for name, view := range before {
+		if !reflect.DeepEqual(c.viewLocked(name), view) {
+			changed = append(changed, name)
+		}
+	}
+	listeners := c.listeners
+	c.mu.Unlock()
+
+	for _, name := range changed {
+		for _, f := range listeners {
+			f(name)
+		}
+	}
+}
+
+func (c *clusterCache) setManagedCluster(obj interface{}) {
+	mcObj, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+	mc := &clusterv1.ManagedCluster{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(mcObj.UnstructuredContent(), &mc); err != nil {
+		klog.Warningf("Error unmarshal ManagedCluster object %v", err)
+		return
+	}
+	info := clusterInfo{
+		uid:    string(mc.GetUID()),
+		claims: map[string]string{},
+		local:  mc.GetLabels()[localClusterLabel] == "true",
+		labels: mc.GetLabels(),
+	}
+	for _, claimInfo := range mc.Status.ClusterClaims {
+		info.claims[claimInfo.Name] = claimInfo.Value
+	}
+
+	c.update(mc.GetName(), func() { c.clusters[mc.GetName()] = info })
+}
+
+func (c *clusterCache) deleteManagedCluster(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	mcObj, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+
+	c.update(mcObj.GetName(), func() { delete(c.clusters, mcObj.GetName()) })
+}
+
+func (c *clusterCache) setClusterVersion(obj interface{}) {
+	cvObj, ok := obj.(*unstructured.Unstructured)
+	if !ok || cvObj.GetName() != clusterVersionName {
+		return
+	}
+	cv := &ocinfrav1.ClusterVersion{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(cvObj.UnstructuredContent(), &cv); err != nil {
+		klog.Warningf("Error unmarshal cluster version object %v", err)
+		return
+	}
+	c.setHubID(string(cv.Spec.ClusterID))
+}
+
+func (c *clusterCache) setHubID(id string) {
+	c.update(hubClusterName, func() { c.hubID = id })
+}
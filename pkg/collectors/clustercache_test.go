@@ -0,0 +1,261 @@
+// Copyright (c) 2026 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"testing"
+	"time"
+
+	ocinfrav1 "github.com/openshift/api/config/v1"
+	"golang.org/x/net/context"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	clienttesting "k8s.io/client-go/testing"
+	mcv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+// newSyncedClusterCache starts a clusterCache on the given client and waits for
+// its informers, ClusterVersions included, to sync. The informers are stopped
+// when the test ends.
+func newSyncedClusterCache(t *testing.T, client dynamic.Interface, localClusterName string, idSources []string) *clusterCache {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.TODO())
+	t.Cleanup(cancel)
+	clusters := newClusterCache(client, localClusterName, idSources)
+	if !clusters.start(ctx) {
+		t.Fatal("cluster cache did not sync")
+	}
+	for gvr, synced := range clusters.factory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			t.Fatalf("%s informer did not sync", gvr)
+		}
+	}
+	return clusters
+}
+
+// waitForClusterID polls the cache until the cluster resolves to want.
+func waitForClusterID(t *testing.T, clusters *clusterCache, clusterName string, want string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for clusters.clusterID(clusterName) != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("cluster %s: expected ID %q got %q", clusterName, want, clusters.clusterID(clusterName))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// updateManagedCluster writes the given ManagedCluster through the client.
+func updateManagedCluster(t *testing.T, client dynamic.Interface, mc *mcv1.ManagedCluster) {
+	t.Helper()
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(mc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mcU := &unstructured.Unstructured{Object: content}
+	mcU.SetAPIVersion(mcv1.SchemeGroupVersion.String())
+	mcU.SetKind("ManagedCluster")
+	if _, err := client.Resource(mcGVR).Update(context.TODO(), mcU, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_clusterCache(t *testing.T) {
+	s := scheme.Scheme
+	s.AddKnownTypes(ocinfrav1.SchemeGroupVersion, &ocinfrav1.ClusterVersion{})
+	s.AddKnownTypes(mcv1.SchemeGroupVersion, &mcv1.ManagedCluster{})
+
+	version := &ocinfrav1.ClusterVersion{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "version",
+		},
+		Spec: ocinfrav1.ClusterVersionSpec{
+			ClusterID: "hub_id",
+		},
+	}
+	mc := &mcv1.ManagedCluster{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ManagedCluster",
+			APIVersion: mcv1.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "importing-cluster",
+		},
+	}
+
+	localMC := &mcv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "hub",
+			Labels: map[string]string{
+				"local-cluster": "true",
+			},
+		},
+	}
+
+	client := fake.NewSimpleDynamicClient(s, version, mc, localMC)
+	clusters := newSyncedClusterCache(t, client, "", []string{"id.openshift.io"})
+
+	waitForClusterID(t, clusters, "hub", "hub_id")
+	waitForClusterID(t, clusters, "local-cluster", "")
+	waitForClusterID(t, clusters, "importing-cluster", "")
+	waitForClusterID(t, clusters, "unknown-cluster", "")
+
+	mc.Status.ClusterClaims = []mcv1.ManagedClusterClaim{
+		{
+			Name:  "id.openshift.io",
+			Value: "imported_id",
+		},
+	}
+	updateManagedCluster(t, client, mc)
+	waitForClusterID(t, clusters, "importing-cluster", "imported_id")
+
+	if err := client.Resource(mcGVR).Delete(context.TODO(), mc.GetName(), metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitForClusterID(t, clusters, "importing-cluster", "")
+}
+
+func Test_clusterCache_withoutClusterVersions(t *testing.T) {
+	s := runtime.NewScheme()
+	s.AddKnownTypes(mcv1.SchemeGroupVersion, &mcv1.ManagedCluster{}, &mcv1.ManagedClusterList{})
+	mc := &mcv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "managed-cluster",
+			UID:  "managed-cluster-uid",
+		},
+	}
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(s, map[schema.GroupVersionResource]string{
+		cvGVR: "ClusterVersionList",
+	}, mc)
+	// Hubs other than OpenShift do not serve ClusterVersions.
+	client.PrependReactor("list", "clusterversions", func(clienttesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewNotFound(cvGVR.GroupResource(), "")
+	})
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+	clusters := newClusterCache(client, "", nil)
+	if !clusters.start(ctx) {
+		t.Fatal("cluster cache did not sync without ClusterVersions")
+	}
+	waitForClusterID(t, clusters, "managed-cluster", "managed-cluster-uid")
+}
+
+func Test_clusterCache_clusterIdentity(t *testing.T) {
+	s := scheme.Scheme
+	s.AddKnownTypes(ocinfrav1.SchemeGroupVersion, &ocinfrav1.ClusterVersion{})
+	s.AddKnownTypes(mcv1.SchemeGroupVersion, &mcv1.ManagedCluster{})
+
+	version := &ocinfrav1.ClusterVersion{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "version",
+		},
+		Spec: ocinfrav1.ClusterVersionSpec{
+			ClusterID: "hub_id",
+		},
+	}
+	hub := &mcv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "local-cluster",
+			UID:  "hub-uid",
+			Labels: map[string]string{
+				"local-cluster": "true",
+			},
+		},
+	}
+	ocp := &mcv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "ocp",
+			UID:  "ocp-uid",
+		},
+		Status: mcv1.ManagedClusterStatus{
+			ClusterClaims: []mcv1.ManagedClusterClaim{
+				{
+					Name:  "id.k8s.io",
+					Value: "ocp_k8s_id",
+				}, {
+					Name:  "id.openshift.io",
+					Value: "ocp_id",
+				},
+			},
+		},
+	}
+	eks := &mcv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "eks",
+			UID:  "eks-uid",
+		},
+		Status: mcv1.ManagedClusterStatus{
+			ClusterClaims: []mcv1.ManagedClusterClaim{
+				{
+					Name:  "id.k8s.io",
+					Value: "eks_k8s_id",
+				},
+			},
+		},
+	}
+	kind := &mcv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "kind",
+			UID:  "kind-uid",
+		},
+	}
+
+	type want struct {
+		id     string
+		source string
+	}
+	tests := []struct {
+		name      string
+		idSources []string
+		want      map[string]want
+	}{
+		{
+			name:      "default",
+			idSources: nil,
+			want: map[string]want{
+				"":              {"hub_id", "clusterversion"},
+				"local-cluster": {"hub_id", "clusterversion"},
+				"ocp":           {"ocp_id", "id.openshift.io"},
+				"eks":           {"eks_k8s_id", "id.k8s.io"},
+				"kind":          {"kind-uid", "uid"},
+				"unknown":       {"", ""},
+			},
+		}, {
+			name:      "openshift only",
+			idSources: []string{"id.openshift.io"},
+			want: map[string]want{
+				"local-cluster": {"hub_id", "clusterversion"},
+				"ocp":           {"ocp_id", "id.openshift.io"},
+				"eks":           {"", ""},
+				"kind":          {"", ""},
+			},
+		}, {
+			name:      "name",
+			idSources: []string{"name"},
+			want: map[string]want{
+				"ocp":  {"ocp", "name"},
+				"eks":  {"eks", "name"},
+				"kind": {"kind", "name"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewSimpleDynamicClient(s, version, hub, ocp, eks, kind)
+			clusters := newSyncedClusterCache(t, client, "", tt.idSources)
+			for clusterName, w := range tt.want {
+				if id, source := clusters.clusterIdentity(clusterName); id != w.id || source != w.source {
+					t.Errorf("cluster %s: expected (%q, %q) got (%q, %q)", clusterName, w.id, w.source, id, source)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,364 @@
+// Copyright (c) 2026 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"context"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// clusterNamespaceReflectors runs the reflectors of the namespaces backing
+// ManagedClusters, and keeps them in sync with the ManagedClusters as they are
+// imported or detached: one reflector per cluster namespace for a few clusters,
+// a single cluster-wide reflector filtering namespaces in process otherwise.
+type clusterNamespaceReflectors struct {
+	clusters     *clusterCache
+	filter       namespaceFilter
+	store        namespaceReplacer
+	expectedType interface{}
+	listWatch    func(ns string) cache.ListWatch
+	// threshold is the number of cluster namespaces above which a single
+	// cluster-wide reflector is run.
+	threshold int
+
+	// changed is signalled, without blocking, when a ManagedCluster is added
+	// or removed.
+	changed chan struct{}
+
+	mu sync.Mutex
+	// exists holds the ManagedClusters known to exist, so that changes to the
+	// identity or labels of a cluster do not signal changed.
+	exists map[string]struct{}
+}
+
+// namespaceReflector is a running reflector.
+type namespaceReflector struct {
+	cancel context.CancelFunc
+	// done is closed once the reflector returned and its objects were dropped
+	// from the store.
+	done chan struct{}
+	// namespaces holds the cluster namespaces the reflector was started for.
+	namespaces map[string]struct{}
+	// store is the store of the cluster-wide reflector, nil for the reflector
+	// of a single namespace.
+	store *clusterWideStore
+}
+
+// newClusterNamespaceReflectors returns a clusterNamespaceReflectors feeding
+// the store with the objects of the namespaces that both back a ManagedCluster
+// of the cache and are selected by the filter.
+func newClusterNamespaceReflectors(
+	clusters *clusterCache,
+	filter namespaceFilter,
+	store namespaceReplacer,
+	expectedType interface{},
+	listWatch func(ns string) cache.ListWatch,
+) *clusterNamespaceReflectors {
+	r := &clusterNamespaceReflectors{
+		clusters:     clusters,
+		filter:       filter,
+		store:        store,
+		expectedType: expectedType,
+		listWatch:    listWatch,
+		threshold:    namespaceReflectorThreshold,
+		changed:      make(chan struct{}, 1),
+		exists:       map[string]struct{}{},
+	}
+	for _, name := range clusters.clusterNames() {
+		r.exists[name] = struct{}{}
+	}
+	clusters.onClusterChange(r.clusterChanged)
+	return r
+}
+
+// clusterChanged signals changed when the ManagedCluster was added or removed.
+func (r *clusterNamespaceReflectors) clusterChanged(clusterName string) {
+	exists := r.clusters.hasCluster(clusterName)
+	r.mu.Lock()
+	_, existed := r.exists[clusterName]
+	if exists {
+		r.exists[clusterName] = struct{}{}
+	} else {
+		delete(r.exists, clusterName)
+	}
+	r.mu.Unlock()
+	if exists == existed {
+		return
+	}
+
+	select {
+	case r.changed <- struct{}{}:
+	default:
+	}
+}
+
+// namespaces returns the sorted cluster namespaces to watch.
+func (r *clusterNamespaceReflectors) namespaces() []string {
+	namespaces := []string{}
+	for _, name := range r.clusters.clusterNames() {
+		if r.filter.matches(name) {
+			namespaces = append(namespaces, name)
+		}
+	}
+	return namespaces
+}
+
+// selected reports whether the objects of the namespace are collected.
+func (r *clusterNamespaceReflectors) selected(namespace string) bool {
+	return r.filter.matches(namespace) && r.clusters.hasCluster(namespace)
+}
+
+// run starts and stops reflectors as ManagedClusters come and go. It blocks
+// until the context is done and every reflector returned.
+func (r *clusterNamespaceReflectors) run(ctx context.Context) {
+	running := map[string]*namespaceReflector{}
+	defer func() {
+		for ns, reflector := range running {
+			r.stop(running, ns, reflector)
+		}
+	}()
+	for {
+		r.sync(ctx, running)
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.changed:
+		}
+	}
+}
+
+// sync starts the reflectors of the new cluster namespaces and stops those of
+// the namespaces gone. running is indexed by namespace, metav1.NamespaceAll for
+// the cluster-wide reflector.
+func (r *clusterNamespaceReflectors) sync(ctx context.Context, running map[string]*namespaceReflector) {
+	namespaces := r.namespaces()
+	wanted := map[string]struct{}{}
+	for _, ns := range namespaces {
+		wanted[ns] = struct{}{}
+	}
+
+	if len(namespaces) > r.threshold {
+		for ns, reflector := range running {
+			if ns != metav1.NamespaceAll {
+				r.stop(running, ns, reflector)
+			}
+		}
+		if all, ok := running[metav1.NamespaceAll]; ok {
+			// The reflector holds the objects of the namespaces it filtered
+			// out, pass on those of the new clusters and drop those of the
+			// clusters gone.
+			for ns := range wanted {
+				if _, ok := all.namespaces[ns]; !ok {
+					all.namespaces[ns] = struct{}{}
+					all.store.release(ns)
+				}
+			}
+			for ns := range all.namespaces {
+				if _, ok := wanted[ns]; !ok {
+					delete(all.namespaces, ns)
+					all.store.hold(ns)
+				}
+			}
+			return
+		}
+		klog.Infof("Watching the namespaces of %d ManagedClusters with a single reflector", len(namespaces))
+		r.start(ctx, running, metav1.NamespaceAll, wanted)
+		return
+	}
+
+	if all, ok := running[metav1.NamespaceAll]; ok {
+		r.stop(running, metav1.NamespaceAll, all)
+	}
+	for ns, reflector := range running {
+		if _, ok := wanted[ns]; !ok {
+			klog.Infof("ManagedCluster %s is gone, no longer watching its namespace", ns)
+			r.stop(running, ns, reflector)
+		}
+	}
+	for _, ns := range namespaces {
+		if _, ok := running[ns]; !ok {
+			klog.Infof("Watching the namespace of ManagedCluster %s", ns)
+			r.start(ctx, running, ns, map[string]struct{}{ns: {}})
+		}
+	}
+}
+
+// start runs a reflector for the namespace in the background.
+func (r *clusterNamespaceReflectors) start(ctx context.Context, running map[string]*namespaceReflector, ns string, namespaces map[string]struct{}) {
+	var store cache.Store = &namespaceStore{namespaceReplacer: r.store, namespace: ns}
+	var allStore *clusterWideStore
+	if ns == metav1.NamespaceAll {
+		allStore = newClusterWideStore(r.store, r.filter, r.clusters.hasCluster)
+		store = allStore
+	}
+	lw := r.listWatch(ns)
+	reflector := cache.NewReflector(&lw, r.expectedType, store, 0)
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	running[ns] = &namespaceReflector{cancel: cancel, done: done, namespaces: namespaces, store: allStore}
+	go func() {
+		defer close(done)
+		reflector.Run(ctx.Done())
+		for ns := range namespaces {
+			if err := r.store.DropNamespace(ns); err != nil {
+				klog.Warningf("Error dropping the objects of namespace %s: %v", ns, err)
+			}
+		}
+	}()
+}
+
+// stop cancels the reflector of the namespace and waits for it to return.
+func (r *clusterNamespaceReflectors) stop(running map[string]*namespaceReflector, ns string, reflector *namespaceReflector) {
+	reflector.cancel()
+	<-reflector.done
+	delete(running, ns)
+}
+
+// clusterWideStore is the store of the cluster-wide reflector. It passes on the
+// objects of the namespaces selected by the filter that back a ManagedCluster,
+// and holds those of the other namespaces selected by the filter, so that they
+// are passed on without a relist when their cluster is imported.
+type clusterWideStore struct {
+	namespaceReplacer
+	filter     namespaceFilter
+	hasCluster func(namespace string) bool
+
+	mu sync.Mutex
+	// objects holds every object of the namespaces selected by the filter,
+	// indexed by namespace and key.
+	objects map[string]map[string]interface{}
+	// released holds the namespaces whose objects are passed on.
+	released map[string]bool
+}
+
+func newClusterWideStore(store namespaceReplacer, filter namespaceFilter, hasCluster func(namespace string) bool) *clusterWideStore {
+	return &clusterWideStore{
+		namespaceReplacer: store,
+		filter:            filter,
+		hasCluster:        hasCluster,
+		objects:           map[string]map[string]interface{}{},
+		released:          map[string]bool{},
+	}
+}
+
+// releasedLocked reports whether the objects of the namespace are passed on,
+// deciding it on the first object of a namespace seen.
+func (s *clusterWideStore) releasedLocked(namespace string) bool {
+	released, ok := s.released[namespace]
+	if !ok {
+		released = s.hasCluster(namespace)
+		s.released[namespace] = released
+	}
+	return released
+}
+
+func (s *clusterWideStore) Add(obj interface{}) error {
+	return s.put(obj, s.namespaceReplacer.Add)
+}
+
+func (s *clusterWideStore) Update(obj interface{}) error {
+	return s.put(obj, s.namespaceReplacer.Update)
+}
+
+// put holds the object, and passes it on to pass if its namespace is
+// released.
+func (s *clusterWideStore) put(obj interface{}, pass func(obj interface{}) error) error {
+	o, err := meta.Accessor(obj)
+	if err != nil || !s.filter.matches(o.GetNamespace()) {
+		return nil
+	}
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.objects[o.GetNamespace()] == nil {
+		s.objects[o.GetNamespace()] = map[string]interface{}{}
+	}
+	s.objects[o.GetNamespace()][key] = obj
+	if !s.releasedLocked(o.GetNamespace()) {
+		return nil
+	}
+	return pass(obj)
+}
+
+func (s *clusterWideStore) Delete(obj interface{}) error {
+	o, err := meta.Accessor(obj)
+	if err != nil || !s.filter.matches(o.GetNamespace()) {
+		return nil
+	}
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects[o.GetNamespace()], key)
+	if len(s.objects[o.GetNamespace()]) == 0 {
+		delete(s.objects, o.GetNamespace())
+	}
+	if !s.releasedLocked(o.GetNamespace()) {
+		return nil
+	}
+	return s.namespaceReplacer.Delete(obj)
+}
+
+func (s *clusterWideStore) Replace(list []interface{}, resourceVersion string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects = map[string]map[string]interface{}{}
+	selected := []interface{}{}
+	for _, obj := range list {
+		o, err := meta.Accessor(obj)
+		if err != nil || !s.filter.matches(o.GetNamespace()) {
+			continue
+		}
+		key, err := cache.MetaNamespaceKeyFunc(obj)
+		if err != nil {
+			return err
+		}
+		if s.objects[o.GetNamespace()] == nil {
+			s.objects[o.GetNamespace()] = map[string]interface{}{}
+		}
+		s.objects[o.GetNamespace()][key] = obj
+		if s.releasedLocked(o.GetNamespace()) {
+			selected = append(selected, obj)
+		}
+	}
+	return s.namespaceReplacer.Replace(selected, resourceVersion)
+}
+
+// release passes on the objects of the namespace, and its future ones.
+func (s *clusterWideStore) release(namespace string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.released[namespace] = true
+	list := make([]interface{}, 0, len(s.objects[namespace]))
+	for _, obj := range s.objects[namespace] {
+		list = append(list, obj)
+	}
+	if err := s.ReplaceNamespace(namespace, list, ""); err != nil {
+		klog.Warningf("Error adding the objects of namespace %s: %v", namespace, err)
+	}
+}
+
+// hold drops the objects of the namespace from the store, and holds back its
+// future ones.
+func (s *clusterWideStore) hold(namespace string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.released[namespace] = false
+	if err := s.DropNamespace(namespace); err != nil {
+		klog.Warningf("Error dropping the objects of namespace %s: %v", namespace, err)
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright (c) 2026 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	ocinfrav1 "github.com/openshift/api/config/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/tools/cache"
+	mcv1 "open-cluster-management.io/api/cluster/v1"
+	pr "sigs.k8s.io/wg-policy-prototypes/policy-report/pkg/api/wgpolicyk8s.io/v1alpha2"
+)
+
+func Test_clusterNamespaceReflectors(t *testing.T) {
+	s := runtime.NewScheme()
+	s.AddKnownTypes(pr.SchemeGroupVersion, &pr.PolicyReport{}, &pr.PolicyReportList{})
+	s.AddKnownTypes(ocinfrav1.SchemeGroupVersion, &ocinfrav1.ClusterVersion{}, &ocinfrav1.ClusterVersionList{})
+	s.AddKnownTypes(mcv1.SchemeGroupVersion, &mcv1.ManagedCluster{}, &mcv1.ManagedClusterList{})
+
+	report := func(namespace string) *pr.PolicyReport {
+		return &pr.PolicyReport{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      namespace,
+				Namespace: namespace,
+				UID:       types.UID(namespace),
+			},
+			Results: []*pr.PolicyReportResult{
+				{
+					Category: "service_availability",
+					Policy:   "MASTER_DEFINED_AS_MACHINESET",
+					Result:   "fail",
+				},
+			},
+		}
+	}
+	series := func(name string) string {
+		return fmt.Sprintf(`policyreport_info{managed_cluster_id=%q,category="service_availability",policy="MASTER_DEFINED_AS_MACHINESET",result="fail",severity="unknown",source="",clusterset=""} 1`, name)
+	}
+
+	tests := []struct {
+		name      string
+		threshold int
+	}{
+		{
+			name:      "per namespace",
+			threshold: namespaceReflectorThreshold,
+		}, {
+			name:      "cluster-wide",
+			threshold: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewSimpleDynamicClient(s,
+				&mcv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster-a"}},
+				&mcv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster-b"}},
+				&mcv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster-denied"}},
+				report("cluster-a"), report("cluster-b"), report("cluster-c"), report("cluster-denied"),
+				report("open-cluster-management"))
+			clusters := newSyncedClusterCache(t, client, "", []string{"name"})
+			prStore, store := newTestPolicyReportStore(clusters, policyReportOptions{})
+			filter, err := newNamespaceFilter([]string{metav1.NamespaceAll}, []string{"cluster-denied"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r := newClusterNamespaceReflectors(clusters, filter, prStore, &unstructured.Unstructured{},
+				func(ns string) cache.ListWatch {
+					return createPolicyReportListWatchWithClient(client, wgPolicyV1alpha2API, reportSelectors{}, ns)
+				})
+			r.threshold = tt.threshold
+			ctx, cancel := context.WithCancel(context.TODO())
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				r.run(ctx)
+			}()
+
+			waitForMetrics(t, store, series("cluster-a"), true)
+			waitForMetrics(t, store, series("cluster-b"), true)
+			for _, name := range []string{"cluster-c", "cluster-denied", "open-cluster-management"} {
+				waitForMetrics(t, store, series(name), false)
+			}
+			lists := func() int {
+				n := 0
+				for _, action := range client.Actions() {
+					if action.GetVerb() == "list" && action.GetResource() == wgPolicyV1alpha2API.gvr() {
+						n++
+					}
+				}
+				return n
+			}
+			listed := lists()
+
+			// An imported cluster gets its reports collected, a detached
+			// one loses them.
+			mc := &unstructured.Unstructured{}
+			mc.SetAPIVersion(mcv1.SchemeGroupVersion.String())
+			mc.SetKind("ManagedCluster")
+			mc.SetName("cluster-c")
+			if _, err := client.Resource(mcGVR).Create(context.TODO(), mc, metav1.CreateOptions{}); err != nil {
+				t.Fatal(err)
+			}
+			waitForMetrics(t, store, series("cluster-c"), true)
+			if err := client.Resource(mcGVR).Delete(context.TODO(), "cluster-a", metav1.DeleteOptions{}); err != nil {
+				t.Fatal(err)
+			}
+			waitForMetrics(t, store, series("cluster-a"), false)
+			waitForMetrics(t, store, series("cluster-b"), true)
+			waitForMetrics(t, store, series("cluster-c"), true)
+
+			// A cluster imported again gets its reports back.
+			mc.SetName("cluster-a")
+			if _, err := client.Resource(mcGVR).Create(context.TODO(), mc, metav1.CreateOptions{}); err != nil {
+				t.Fatal(err)
+			}
+			waitForMetrics(t, store, series("cluster-a"), true)
+			// The cluster-wide reflector passes on the reports it held
+			// back rather than relisting.
+			if got := lists() - listed; tt.threshold == 1 && got != 0 {
+				t.Errorf("expected no relist got %d", got)
+			}
+
+			cancel()
+			<-done
+			waitForMetrics(t, store, series("cluster-b"), false)
+		})
+	}
+}
+
+func Test_clusterNamespaceReflectors_changed(t *testing.T) {
+	clusters := newClusterCache(fake.NewSimpleDynamicClient(runtime.NewScheme()), "", nil)
+	filter, err := newNamespaceFilter([]string{metav1.NamespaceAll}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := newClusterNamespaceReflectors(clusters, filter, nil, nil, nil)
+	setCluster := func(labels map[string]string) {
+		obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&mcv1.ManagedCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster-a", Labels: labels},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		clusters.setManagedCluster(&unstructured.Unstructured{Object: obj})
+	}
+	expectChanged := func(want bool) {
+		t.Helper()
+		select {
+		case <-r.changed:
+			if !want {
+				t.Error("expected no change to be signalled")
+			}
+		default:
+			if want {
+				t.Error("expected a change to be signalled")
+			}
+		}
+	}
+
+	setCluster(nil)
+	expectChanged(true)
+	setCluster(map[string]string{"env": "prod"})
+	expectChanged(false)
+	clusters.deleteManagedCluster(&unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "cluster-a"},
+	}})
+	expectChanged(true)
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2026 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"k8s.io/kube-state-metrics/pkg/metric"
+)
+
+var (
+	descClusterPolicyReportLabelsName = "clusterpolicyreport_info"
+	descClusterPolicyReportLabelsHelp = "Open Cluster Management ClusterPolicyReport Info."
+)
+
+// getClusterPolicyReportMetricFamilies returns the families generated from
+// ClusterPolicyReports. They are hub-scoped, so their results are reported
+// against the hub's own cluster ID.
+func getClusterPolicyReportMetricFamilies(clusters *clusterCache, opts resultOptions) []metric.FamilyGenerator {
+	infoLabelKeys := append(append([]string{}, descPolicyReportDefaultLabels...), descPolicyReportClusterSetLabel)
+
+	return []metric.FamilyGenerator{
+		{
+			Name: descClusterPolicyReportLabelsName,
+			Type: metric.Gauge,
+			Help: descClusterPolicyReportLabelsHelp,
+			GenerateFunc: wrapPolicyReportFunc(func(cprObj *unstructured.Unstructured) metric.Family {
+				cpr, err := decodePolicyReport(cprObj)
+				if err != nil {
+					klog.Infof("Error unstructuring ClusterPolicyReport ")
+					return metric.Family{Metrics: []*metric.Metric{}}
+				}
+				clusterId := clusters.clusterID(hubClusterName)
+				clusterSet := clusters.clusterLabels(hubClusterName)[clusterSetLabel]
+
+				f := metric.Family{}
+
+				for result, val := range getResults(clusterId, cpr, opts) {
+					f.Metrics = append(f.Metrics, &metric.Metric{
+						LabelKeys:   infoLabelKeys,
+						LabelValues: append(result.values(), clusterSet),
+						Value:       float64(val),
+					})
+				}
+				return f
+			}),
+		},
+	}
+}
+
+func createClusterPolicyReportListWatchWithClient(client dynamic.Interface, api reportAPI, selectors reportSelectors) cache.ListWatch {
+	return cache.ListWatch{
+		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			return client.Resource(api.clusterGVR()).List(context.TODO(), selectors.apply(opts))
+		},
+		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			return client.Resource(api.clusterGVR()).Watch(context.TODO(), selectors.apply(opts))
+		},
+	}
+}
@@ -0,0 +1,240 @@
+// Copyright (c) 2026 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	ocinfrav1 "github.com/openshift/api/config/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/kube-state-metrics/pkg/metric"
+	metricsstore "k8s.io/kube-state-metrics/pkg/metrics_store"
+	mcv1 "open-cluster-management.io/api/cluster/v1"
+	pr "sigs.k8s.io/wg-policy-prototypes/policy-report/pkg/api/wgpolicyk8s.io/v1alpha2"
+)
+
+func newClusterPolicyReport(t *testing.T) *unstructured.Unstructured {
+	t.Helper()
+	cpr := &pr.ClusterPolicyReport{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "hub-findings",
+			UID:  "hub-findings-uid",
+		},
+		Results: []*pr.PolicyReportResult{
+			{
+				Category: "openshift,configuration,service_availability",
+				Policy:   "MASTER_DEFINED_AS_MACHINESET",
+				Result:   "fail",
+				Properties: map[string]string{
+					"total_risk": "4",
+				},
+			}, { // an exact duplicate of the first:
+				Category: "openshift,configuration,service_availability",
+				Policy:   "MASTER_DEFINED_AS_MACHINESET",
+				Result:   "fail",
+				Properties: map[string]string{
+					"total_risk": "4",
+				},
+			}, {
+				Category: "security",
+				Policy:   "AUDIT_LOG_DISABLED",
+				Result:   "warn",
+			},
+		},
+	}
+	cprU := &unstructured.Unstructured{}
+	if err := scheme.Scheme.Convert(cpr, cprU, nil); err != nil {
+		t.Fatal(err)
+	}
+	return cprU
+}
+
+func Test_getClusterPolicyReportMetricFamilies(t *testing.T) {
+	s := scheme.Scheme
+	s.AddKnownTypes(pr.SchemeGroupVersion, &pr.ClusterPolicyReport{})
+	s.AddKnownTypes(ocinfrav1.SchemeGroupVersion, &ocinfrav1.ClusterVersion{})
+	s.AddKnownTypes(mcv1.SchemeGroupVersion, &mcv1.ManagedCluster{})
+
+	version := &ocinfrav1.ClusterVersion{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "version",
+		},
+		Spec: ocinfrav1.ClusterVersionSpec{
+			ClusterID: "mycluster_id",
+		},
+	}
+	localMC := &mcv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "local-cluster",
+			Labels: map[string]string{
+				"local-cluster": "true",
+				"cluster.open-cluster-management.io/clusterset": "hub-set",
+			},
+		},
+		Status: mcv1.ManagedClusterStatus{
+			ClusterClaims: []mcv1.ManagedClusterClaim{
+				{
+					Name:  "id.openshift.io",
+					Value: "local_claim_id",
+				},
+			},
+		},
+	}
+	// A hub that is not OpenShift has a ClusterVersion without ID, if any.
+	noIDVersion := &ocinfrav1.ClusterVersion{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "version",
+		},
+	}
+	spokeMC := &mcv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "managed-cluster",
+		},
+	}
+	cprU := newClusterPolicyReport(t)
+
+	tests := []struct {
+		name    string
+		objects []runtime.Object
+		want    string
+	}{
+		{
+			name:    "hub ClusterVersion",
+			objects: []runtime.Object{version, localMC, spokeMC},
+			want: strings.Join([]string{
+				`clusterpolicyreport_info{managed_cluster_id="mycluster_id",category="openshift,configuration,service_availability",policy="MASTER_DEFINED_AS_MACHINESET",result="fail",severity="critical",source="",clusterset="hub-set"} 2`,
+				`clusterpolicyreport_info{managed_cluster_id="mycluster_id",category="security",policy="AUDIT_LOG_DISABLED",result="warn",severity="unknown",source="",clusterset="hub-set"} 1`,
+			}, "\n"),
+		}, {
+			name:    "no ClusterVersion ID",
+			objects: []runtime.Object{noIDVersion, localMC, spokeMC},
+			want: strings.Join([]string{
+				`clusterpolicyreport_info{managed_cluster_id="local_claim_id",category="openshift,configuration,service_availability",policy="MASTER_DEFINED_AS_MACHINESET",result="fail",severity="critical",source="",clusterset="hub-set"} 2`,
+				`clusterpolicyreport_info{managed_cluster_id="local_claim_id",category="security",policy="AUDIT_LOG_DISABLED",result="warn",severity="unknown",source="",clusterset="hub-set"} 1`,
+			}, "\n"),
+		}, {
+			name:    "no hub identity",
+			objects: []runtime.Object{noIDVersion, spokeMC},
+			want:    "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewSimpleDynamicClient(s, tt.objects...)
+			clusters := newSyncedClusterCache(t, client, "", nil)
+			c := generateMetricsTestCase{
+				Obj:         cprU,
+				MetricNames: []string{"clusterpolicyreport_info"},
+				Want:        tt.want,
+				Func:        metric.ComposeMetricGenFuncs(getClusterPolicyReportMetricFamilies(clusters, resultOptions{})),
+			}
+			if err := c.run(); err != nil {
+				t.Errorf("unexpected collecting result:\n%s", err)
+			}
+		})
+	}
+}
+
+func Test_clusterPolicyReportStore(t *testing.T) {
+	s := scheme.Scheme
+	s.AddKnownTypes(pr.SchemeGroupVersion, &pr.ClusterPolicyReport{})
+	s.AddKnownTypes(ocinfrav1.SchemeGroupVersion, &ocinfrav1.ClusterVersion{})
+	s.AddKnownTypes(mcv1.SchemeGroupVersion, &mcv1.ManagedCluster{})
+
+	version := &ocinfrav1.ClusterVersion{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "version",
+		},
+	}
+	localMC := &mcv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "local-cluster",
+			Labels: map[string]string{
+				"local-cluster": "true",
+			},
+		},
+	}
+	cprU := newClusterPolicyReport(t)
+
+	client := fake.NewSimpleDynamicClient(s, version, localMC)
+	clusters := newSyncedClusterCache(t, client, "", []string{"id.openshift.io"})
+	families := getClusterPolicyReportMetricFamilies(clusters, resultOptions{})
+	store := metricsstore.NewMetricsStore(
+		metric.ExtractMetricFamilyHeaders(families),
+		metric.ComposeMetricGenFuncs(families),
+	)
+	cprStore := newPolicyReportStore(store, clusters)
+
+	if err := cprStore.Add(cprU); err != nil {
+		t.Fatal(err)
+	}
+	waitForMetrics(t, store, "clusterpolicyreport_info{", false)
+
+	localMC.Labels["cluster.open-cluster-management.io/clusterset"] = "hub-set"
+	localMC.Status.ClusterClaims = []mcv1.ManagedClusterClaim{
+		{
+			Name:  "id.openshift.io",
+			Value: "local_claim_id",
+		},
+	}
+	updateManagedCluster(t, client, localMC)
+	waitForMetrics(t, store, `clusterpolicyreport_info{managed_cluster_id="local_claim_id",category="security",policy="AUDIT_LOG_DISABLED",result="warn",severity="unknown",source="",clusterset="hub-set"} 1`, true)
+
+	if err := cprStore.Delete(cprU); err != nil {
+		t.Fatal(err)
+	}
+	waitForMetrics(t, store, "clusterpolicyreport_info{", false)
+}
+
+func Test_createClusterPolicyReportListWatchWithClient(t *testing.T) {
+	s := runtime.NewScheme()
+	s.AddKnownTypes(pr.SchemeGroupVersion, &pr.ClusterPolicyReport{})
+	s.AddKnownTypes(pr.SchemeGroupVersion, &pr.ClusterPolicyReportList{})
+
+	cpr := &pr.ClusterPolicyReport{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ClusterPolicyReport",
+			APIVersion: "wgpolicyk8s.io/v1alpha2",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "hub-findings",
+		},
+		Results: []*pr.PolicyReportResult{
+			{
+				Category: "security",
+				Policy:   "AUDIT_LOG_DISABLED",
+				Result:   "warn",
+			},
+		},
+	}
+	cprU := &unstructured.Unstructured{}
+	if err := scheme.Scheme.Convert(cpr, cprU, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	client := fake.NewSimpleDynamicClient(s, cpr)
+	lw := createClusterPolicyReportListWatchWithClient(client, wgPolicyV1alpha2API, reportSelectors{})
+	l, err := lw.ListFunc(metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lU := l.(*unstructured.UnstructuredList)
+	if len(lU.Items) != 1 {
+		t.Fatalf("expected a list of 1 element got %d", len(lU.Items))
+	}
+	if !reflect.DeepEqual(lU.Items[0], *cprU) {
+		t.Errorf("expected %v got %v", *cprU, lU.Items[0])
+	}
+	w, err := lw.WatchFunc(metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Stop()
+}
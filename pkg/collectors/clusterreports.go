@@ -0,0 +1,328 @@
+// Copyright (c) 2026 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"sort"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"k8s.io/kube-state-metrics/pkg/metric"
+)
+
+// clusterReports is the set of PolicyReports of a cluster namespace, from which
+// the families describing the cluster as a whole are generated. A namespace can
+// hold a report per engine, such as Insights, Kyverno and Gatekeeper, so these
+// families cannot be generated report by report without duplicating series.
+type clusterReports struct {
+	metav1.ObjectMeta
+	reports []*policyReport
+}
+
+// newClusterReports returns the clusterReports of the namespace, named and
+// identified by it, holding the given reports sorted by name. The reports that
+// cannot be decoded are left out.
+func newClusterReports(namespace string, objs []interface{}) *clusterReports {
+	cr := &clusterReports{
+		ObjectMeta: metav1.ObjectMeta{Name: namespace, Namespace: namespace, UID: types.UID(namespace)},
+	}
+	for _, obj := range objs {
+		prObj, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		pr, err := decodePolicyReport(prObj)
+		if err != nil {
+			klog.Infof("Error unstructuring PolicyReport %s/%s: %v", prObj.GetNamespace(), prObj.GetName(), err)
+			continue
+		}
+		cr.reports = append(cr.reports, pr)
+	}
+	sort.Slice(cr.reports, func(i, j int) bool {
+		return cr.reports[i].GetName() < cr.reports[j].GetName()
+	})
+	return cr
+}
+
+// getClusterReportMetricFamilies returns the families generated from the
+// clusterReports of each cluster namespace, with a single series per cluster
+// however many reports its namespace holds.
+func getClusterReportMetricFamilies(clusters *clusterCache, opts policyReportOptions) []metric.FamilyGenerator {
+	clusterLabels, clusterLabelKeys := clusterLabelNames(opts.clusterLabels)
+	clusterLabels = append([]string{clusterSetLabel}, clusterLabels...)
+	clusterLabelKeys = append([]string{descPolicyReportClusterSetLabel}, clusterLabelKeys...)
+	infoLabelKeys := append(append([]string{}, descPolicyReportDefaultLabels...), clusterLabelKeys...)
+	clusterIDLabelKeys := append(append([]string{}, descPolicyReportClusterIDLabels...), descPolicyReportClusterSetLabel)
+	categoryLabelKeys := append(append([]string{}, descPolicyReportCategoryLabels...), descPolicyReportClusterSetLabel)
+	summaryLabelKeys := append(append([]string{}, descPolicyReportSummaryLabels...), descPolicyReportClusterSetLabel)
+	lastUpdatedLabelKeys := append(append([]string{}, descPolicyReportLastUpdatedLabels...), descPolicyReportClusterSetLabel)
+
+	families := []metric.FamilyGenerator{
+		{
+			Name: descPolicyReportLabelsName,
+			Type: metric.Gauge,
+			Help: descPolicyReportLabelsHelp,
+			GenerateFunc: wrapClusterReportsFunc(func(cr *clusterReports) metric.Family {
+				klog.V(2).Infof("Getting PolicyReport Info for Cluster Name %s", cr.GetName())
+				clusterName := cr.GetName()
+				clusterId := clusters.clusterID(clusterName)
+				clusterLabelValues := clusterLabelValues(clusters.clusterLabels(clusterName), clusterLabels)
+
+				f := metric.Family{}
+
+				// The same result reported in several reports is counted in a
+				// single series.
+				results := map[metricResult]int{}
+				for _, pr := range cr.reports {
+					for result, val := range getResults(clusterId, pr, opts.results) {
+						results[result] += val
+					}
+				}
+				for result, val := range results {
+					f.Metrics = append(f.Metrics, &metric.Metric{
+						LabelKeys:   infoLabelKeys,
+						LabelValues: append(result.values(), clusterLabelValues...),
+						Value:       float64(val),
+					})
+				}
+				return f
+			}),
+		},
+		{
+			Name: descPolicyReportClusterIDName,
+			Type: metric.Gauge,
+			Help: descPolicyReportClusterIDHelp,
+			GenerateFunc: wrapClusterReportsFunc(func(cr *clusterReports) metric.Family {
+				clusterName := cr.GetName()
+				clusterId, source := clusters.clusterIdentity(clusterName)
+				if clusterId == "" || len(cr.reports) == 0 {
+					return metric.Family{Metrics: []*metric.Metric{}}
+				}
+				return metric.Family{
+					Metrics: []*metric.Metric{
+						{
+							LabelKeys:   clusterIDLabelKeys,
+							LabelValues: []string{clusterId, clusterName, source, clusters.clusterLabels(clusterName)[clusterSetLabel]},
+							Value:       1,
+						},
+					},
+				}
+			}),
+		},
+		{
+			Name: descPolicyReportCategoryName,
+			Type: metric.Gauge,
+			Help: descPolicyReportCategoryHelp,
+			GenerateFunc: wrapClusterReportsFunc(func(cr *clusterReports) metric.Family {
+				clusterName := cr.GetName()
+				clusterId := clusters.clusterID(clusterName)
+				clusterSet := clusters.clusterLabels(clusterName)[clusterSetLabel]
+
+				f := metric.Family{}
+
+				for _, c := range getCategories(clusterId, cr.reports, opts.results) {
+					f.Metrics = append(f.Metrics, &metric.Metric{
+						LabelKeys:   categoryLabelKeys,
+						LabelValues: []string{clusterId, c.policy, c.category, clusterSet},
+						Value:       1,
+					})
+				}
+				return f
+			}),
+		},
+		{
+			Name: descPolicyReportSummaryName,
+			Type: metric.Gauge,
+			Help: descPolicyReportSummaryHelp,
+			GenerateFunc: wrapClusterReportsFunc(func(cr *clusterReports) metric.Family {
+				clusterName := cr.GetName()
+				clusterId := clusters.clusterID(clusterName)
+				clusterSet := clusters.clusterLabels(clusterName)[clusterSetLabel]
+
+				f := metric.Family{}
+
+				for summary, val := range getSummary(clusterId, cr.reports, opts.results) {
+					f.Metrics = append(f.Metrics, &metric.Metric{
+						LabelKeys:   summaryLabelKeys,
+						LabelValues: []string{clusterId, summary.result, summary.severity, clusterSet},
+						Value:       float64(val),
+					})
+				}
+				return f
+			}),
+		},
+		{
+			Name: descPolicyReportLastUpdatedName,
+			Type: metric.Gauge,
+			Help: descPolicyReportLastUpdatedHelp,
+			GenerateFunc: wrapClusterReportsFunc(func(cr *clusterReports) metric.Family {
+				clusterName := cr.GetName()
+				clusterId := clusters.clusterID(clusterName)
+				updated := cr.lastUpdated()
+				if clusterId == "" || updated.IsZero() {
+					return metric.Family{Metrics: []*metric.Metric{}}
+				}
+				return metric.Family{
+					Metrics: []*metric.Metric{
+						{
+							LabelKeys:   lastUpdatedLabelKeys,
+							LabelValues: []string{clusterId, clusters.clusterLabels(clusterName)[clusterSetLabel]},
+							Value:       float64(updated.Unix()),
+						},
+					},
+				}
+			}),
+		},
+	}
+
+	if opts.maxResultResources > 0 {
+		resourcesLabelKeys := append(append([]string{}, descPolicyReportResourcesLabels...), descPolicyReportClusterSetLabel)
+		droppedLabelKeys := append(append([]string{}, descPolicyReportResourcesDroppedLabels...), descPolicyReportClusterSetLabel)
+		families = append(families, metric.FamilyGenerator{
+			Name: descPolicyReportResourcesName,
+			Type: metric.Gauge,
+			Help: descPolicyReportResourcesHelp,
+			GenerateFunc: wrapClusterReportsFunc(func(cr *clusterReports) metric.Family {
+				clusterName := cr.GetName()
+				clusterId := clusters.clusterID(clusterName)
+				clusterSet := clusters.clusterLabels(clusterName)[clusterSetLabel]
+
+				f := metric.Family{}
+
+				resources, _ := getResultResources(clusterId, cr.reports, opts.results, opts.maxResultResources)
+				for _, r := range resources {
+					f.Metrics = append(f.Metrics, &metric.Metric{
+						LabelKeys:   resourcesLabelKeys,
+						LabelValues: []string{clusterId, r.policy, r.kind, r.namespace, r.name, clusterSet},
+						Value:       1,
+					})
+				}
+				return f
+			}),
+		}, metric.FamilyGenerator{
+			Name: descPolicyReportResourcesDroppedName,
+			Type: metric.Gauge,
+			Help: descPolicyReportResourcesDroppedHelp,
+			GenerateFunc: wrapClusterReportsFunc(func(cr *clusterReports) metric.Family {
+				clusterName := cr.GetName()
+				clusterId := clusters.clusterID(clusterName)
+				if clusterId == "" {
+					return metric.Family{Metrics: []*metric.Metric{}}
+				}
+				_, dropped := getResultResources(clusterId, cr.reports, opts.results, opts.maxResultResources)
+				return metric.Family{
+					Metrics: []*metric.Metric{
+						{
+							LabelKeys:   droppedLabelKeys,
+							LabelValues: []string{clusterId, clusters.clusterLabels(clusterName)[clusterSetLabel]},
+							Value:       float64(dropped),
+						},
+					},
+				}
+			}),
+		})
+	}
+
+	if opts.maxReportAge > 0 {
+		families = append(families, metric.FamilyGenerator{
+			Name: descPolicyReportStaleName,
+			Type: metric.Gauge,
+			Help: descPolicyReportStaleHelp,
+			GenerateFunc: wrapClusterReportsFunc(func(cr *clusterReports) metric.Family {
+				clusterName := cr.GetName()
+				clusterId := clusters.clusterID(clusterName)
+				updated := cr.lastUpdated()
+				if clusterId == "" || updated.IsZero() {
+					return metric.Family{Metrics: []*metric.Metric{}}
+				}
+				stale := 0.0
+				if now().Sub(updated) > opts.maxReportAge {
+					stale = 1
+				}
+				return metric.Family{
+					Metrics: []*metric.Metric{
+						{
+							LabelKeys:   lastUpdatedLabelKeys,
+							LabelValues: []string{clusterId, clusters.clusterLabels(clusterName)[clusterSetLabel]},
+							Value:       stale,
+						},
+					},
+				}
+			}),
+		})
+	}
+
+	if opts.findings != nil {
+		firstSeenLabelKeys := append(append([]string{}, descPolicyReportFirstSeenLabels...), descPolicyReportClusterSetLabel)
+		families = append(families, metric.FamilyGenerator{
+			Name: descPolicyReportFirstSeenName,
+			Type: metric.Gauge,
+			Help: descPolicyReportFirstSeenHelp,
+			GenerateFunc: wrapClusterReportsFunc(func(cr *clusterReports) metric.Family {
+				clusterName := cr.GetName()
+				clusterId := clusters.clusterID(clusterName)
+				clusterSet := clusters.clusterLabels(clusterName)[clusterSetLabel]
+
+				f := metric.Family{}
+
+				// A finding open in several reports is a single series.
+				seen := map[string]struct{}{}
+				policies := make([]string, 0)
+				for _, pr := range cr.reports {
+					for policy := range opts.findings.findings(pr) {
+						if _, ok := seen[policy]; !ok {
+							seen[policy] = struct{}{}
+							policies = append(policies, policy)
+						}
+					}
+				}
+				sort.Strings(policies)
+				for _, policy := range policies {
+					firstSeen := opts.findings.firstSeenOf(clusterName, policy)
+					if clusterId == "" || firstSeen.IsZero() {
+						continue
+					}
+					f.Metrics = append(f.Metrics, &metric.Metric{
+						LabelKeys:   firstSeenLabelKeys,
+						LabelValues: []string{clusterId, policy, clusterSet},
+						Value:       float64(firstSeen.Unix()),
+					})
+				}
+				return f
+			}),
+		})
+	}
+
+	return families
+}
+
+// lastUpdated returns the last update of the most recently updated report, or
+// the zero time if none carries a timestamp.
+func (cr *clusterReports) lastUpdated() time.Time {
+	var updated time.Time
+	for _, pr := range cr.reports {
+		if t := lastUpdated(pr); t.After(updated) {
+			updated = t
+		}
+	}
+	return updated
+}
+
+func wrapClusterReportsFunc(f func(*clusterReports) metric.Family) func(interface{}) *metric.Family {
+	return func(obj interface{}) *metric.Family {
+		cr := obj.(*clusterReports)
+
+		metricFamily := f(cr)
+
+		for _, m := range metricFamily.Metrics {
+			m.LabelKeys = append([]string{}, m.LabelKeys...)
+			m.LabelValues = append([]string{}, m.LabelValues...)
+		}
+
+		return &metricFamily
+	}
+}
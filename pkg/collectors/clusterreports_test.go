@@ -0,0 +1,175 @@
+// Copyright (c) 2026 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/kube-state-metrics/pkg/metric"
+	metricsstore "k8s.io/kube-state-metrics/pkg/metrics_store"
+	mcv1 "open-cluster-management.io/api/cluster/v1"
+	pr "sigs.k8s.io/wg-policy-prototypes/policy-report/pkg/api/wgpolicyk8s.io/v1alpha2"
+)
+
+// newClusterReportsFixture returns a cluster cache knowing the managed-cluster
+// ManagedCluster, and the Insights and Kyverno reports of its namespace.
+func newClusterReportsFixture(t *testing.T) (*clusterCache, *unstructured.Unstructured, *unstructured.Unstructured) {
+	t.Helper()
+	mc := &mcv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "managed-cluster",
+			Labels: map[string]string{
+				"cluster.open-cluster-management.io/clusterset": "team-a",
+			},
+		},
+		Status: mcv1.ManagedClusterStatus{
+			ClusterClaims: []mcv1.ManagedClusterClaim{
+				{
+					Name:  "id.openshift.io",
+					Value: "managed-cluster-id",
+				},
+			},
+		},
+	}
+	insights := &pr.PolicyReport{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "insights",
+			Namespace: "managed-cluster",
+			UID:       "insights-uid",
+		},
+		Results: []*pr.PolicyReportResult{
+			{
+				Source:   "insights",
+				Category: "service_availability",
+				Policy:   "MASTER_DEFINED_AS_MACHINESET",
+				Result:   "fail",
+				Properties: map[string]string{
+					"total_risk": "3",
+				},
+			},
+		},
+	}
+	kyverno := &pr.PolicyReport{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kyverno",
+			Namespace: "managed-cluster",
+			UID:       "kyverno-uid",
+		},
+		Results: []*pr.PolicyReportResult{
+			{
+				Source:   "kyverno",
+				Category: "security",
+				Policy:   "disallow-privileged",
+				Result:   "fail",
+			},
+		},
+	}
+	clusters, reports := newPolicyReportFixture(t, policyReportFixture{
+		managedClusters: []*mcv1.ManagedCluster{mc},
+		reports:         []*pr.PolicyReport{insights, kyverno},
+	})
+	return clusters, reports[0], reports[1]
+}
+
+func Test_getClusterReportMetricFamilies(t *testing.T) {
+	clusters, insights, kyverno := newClusterReportsFixture(t)
+	both := newClusterReports("managed-cluster", []interface{}{insights, kyverno})
+
+	tests := []generateMetricsTestCase{
+		{
+			Obj:         both,
+			MetricNames: []string{"policyreport_cluster_id_info"},
+			Want:        `policyreport_cluster_id_info{managed_cluster_id="managed-cluster-id",cluster_name="managed-cluster",cluster_id_source="id.openshift.io",clusterset="team-a"} 1`,
+		}, {
+			Obj:         both,
+			MetricNames: []string{"policyreport_summary"},
+			Want: strings.Join([]string{
+				`policyreport_summary{managed_cluster_id="managed-cluster-id",result="fail",severity="important",clusterset="team-a"} 1`,
+				`policyreport_summary{managed_cluster_id="managed-cluster-id",result="fail",severity="unknown",clusterset="team-a"} 1`,
+			}, "\n"),
+		}, {
+			Obj:         newClusterReports("managed-cluster", []interface{}{insights, insights}),
+			MetricNames: []string{"policyreport_summary"},
+			Want:        `policyreport_summary{managed_cluster_id="managed-cluster-id",result="fail",severity="important",clusterset="team-a"} 2`,
+		}, {
+			Obj:         newClusterReports("managed-cluster", nil),
+			MetricNames: []string{"policyreport_cluster_id_info", "policyreport_summary"},
+			Want:        ``,
+		},
+	}
+	for i, c := range tests {
+		c.Func = metric.ComposeMetricGenFuncs(getClusterReportMetricFamilies(clusters, policyReportOptions{}))
+		if err := c.run(); err != nil {
+			t.Errorf("unexpected collecting result in %v run:\n%s", i, err)
+		}
+	}
+}
+
+func Test_policyReportStore_perCluster(t *testing.T) {
+	clusters, insights, kyverno := newClusterReportsFixture(t)
+	prStore, clusterStore := newTestPolicyReportStore(clusters, policyReportOptions{})
+
+	series := func() int {
+		buf := &bytes.Buffer{}
+		clusterStore.WriteAll(buf)
+		return strings.Count(buf.String(), "policyreport_cluster_id_info{")
+	}
+
+	if err := prStore.Replace([]interface{}{insights, kyverno}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := series(); got != 1 {
+		t.Errorf("expected 1 series for two reports got %d", got)
+	}
+	if err := prStore.Delete(insights); err != nil {
+		t.Fatal(err)
+	}
+	if got := series(); got != 1 {
+		t.Errorf("expected 1 series for the report left got %d", got)
+	}
+	if err := prStore.ReplaceNamespace("managed-cluster", nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := series(); got != 0 {
+		t.Errorf("expected no series without reports got %d", got)
+	}
+}
+
+func Test_policyReportStore_Resync(t *testing.T) {
+	clusters, insights, kyverno := newClusterReportsFixture(t)
+	generated := map[string]int{}
+	counting := func(name string) *metricsstore.MetricsStore {
+		families := []metric.FamilyGenerator{
+			{
+				Name: name,
+				Type: metric.Gauge,
+				GenerateFunc: func(interface{}) *metric.Family {
+					generated[name]++
+					return &metric.Family{}
+				},
+			},
+		}
+		return metricsstore.NewMetricsStore(
+			metric.ExtractMetricFamilyHeaders(families),
+			metric.ComposeMetricGenFuncs(families),
+		)
+	}
+	prStore := newPolicyReportStore(counting("report"), clusters)
+	prStore.perCluster = counting("cluster")
+
+	if err := prStore.Replace([]interface{}{insights, kyverno}, ""); err != nil {
+		t.Fatal(err)
+	}
+	generated = map[string]int{}
+	if err := prStore.Resync(); err != nil {
+		t.Fatal(err)
+	}
+	if generated["report"] != 0 || generated["cluster"] != 1 {
+		t.Errorf("expected only the cluster namespace regenerated got %v", generated)
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright (c) 2026 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// readConfig returns the content of a configuration file, or of the given key
+// of a ConfigMap given as namespace/name read through the client. It returns
+// nil when neither is given.
+func readConfig(client dynamic.Interface, file string, configMap string, key string) ([]byte, error) {
+	switch {
+	case file != "" && configMap != "":
+		return nil, errors.New("configuration is read from a file or a ConfigMap, not both")
+	case file != "":
+		return os.ReadFile(file)
+	case configMap != "":
+		namespace, name, ok := strings.Cut(configMap, "/")
+		if !ok || namespace == "" || name == "" {
+			return nil, fmt.Errorf("ConfigMap %q is not namespace/name", configMap)
+		}
+		cm, err := client.Resource(cmGVR).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		data, found, err := unstructured.NestedString(cm.Object, "data", key)
+		if err != nil || !found {
+			return nil, fmt.Errorf("ConfigMap %s has no %s", configMap, key)
+		}
+		return []byte(data), nil
+	}
+	return nil, nil
+}
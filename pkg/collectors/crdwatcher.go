@@ -0,0 +1,202 @@
+// Copyright (c) 2026 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// crdWatcher runs the reflectors of the collectors only while the
+// CustomResourceDefinition of their resource is established, so that a
+// collector can be built before its CRD is installed and stops when the CRD is
+// removed.
+type crdWatcher struct {
+	ctx      context.Context
+	factory  dynamicinformer.DynamicSharedInformerFactory
+	informer cache.SharedIndexInformer
+
+	mu sync.Mutex
+	// gates holds the gated collectors indexed by the name of each CRD they
+	// can run on.
+	gates map[string][]*crdGate
+}
+
+// crdGate is a collector run while the CRD of one of its resources is
+// established.
+type crdGate struct {
+	collector string
+	// gvrs are the resources the collector can run on, most preferred first.
+	gvrs []schema.GroupVersionResource
+	// run blocks running the reflectors of the collector on the given resource
+	// until the context is done.
+	run func(ctx context.Context, gvr schema.GroupVersionResource)
+	// stopped is called once run returned, to drop what the reflectors stored.
+	stopped func()
+
+	cancel context.CancelFunc
+	// running is the resource of the current run.
+	running schema.GroupVersionResource
+	// done is closed when the last run of the collector returned.
+	done chan struct{}
+}
+
+// newCRDWatcher returns a crdWatcher whose informer uses the given client and
+// runs until the context is done.
+func newCRDWatcher(ctx context.Context, client dynamic.Interface) *crdWatcher {
+	w := &crdWatcher{
+		ctx:     ctx,
+		factory: dynamicinformer.NewDynamicSharedInformerFactory(client, 0),
+		gates:   map[string][]*crdGate{},
+	}
+
+	w.informer = w.factory.ForResource(crdGVR).Informer()
+	if _, err := w.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { w.sync(crdName(obj)) },
+		UpdateFunc: func(_, obj interface{}) { w.sync(crdName(obj)) },
+		DeleteFunc: func(obj interface{}) { w.sync(crdName(obj)) },
+	}); err != nil {
+		klog.Fatalf("cannot watch CustomResourceDefinitions: %v", err)
+	}
+	w.factory.Start(ctx.Done())
+	return w
+}
+
+// runWhileEstablished calls run in the background whenever the CRD of the given
+// resource becomes established and serves its version, and cancels it when the
+// CRD goes away. stopped is called after each run returned. The readiness of
+// the collector is exposed by CollectorReadyMetric.
+func (w *crdWatcher) runWhileEstablished(collector string, gvr schema.GroupVersionResource, run func(ctx context.Context), stopped func()) {
+	w.runWhileAnyEstablished(collector, []schema.GroupVersionResource{gvr}, func(ctx context.Context, _ schema.GroupVersionResource) {
+		run(ctx)
+	}, stopped)
+}
+
+// runWhileAnyEstablished is runWhileEstablished for a collector able to run on
+// any of the given resources, most preferred first. It runs on the most
+// preferred resource whose CRD is established, and starts over on another one
+// when that changes, so that a preferred CRD installed later is picked up.
+func (w *crdWatcher) runWhileAnyEstablished(collector string, gvrs []schema.GroupVersionResource, run func(ctx context.Context, gvr schema.GroupVersionResource), stopped func()) {
+	done := make(chan struct{})
+	close(done)
+	g := &crdGate{
+		collector: collector,
+		gvrs:      gvrs,
+		run:       run,
+		stopped:   stopped,
+		done:      done,
+	}
+
+	// Versions of a resource share its CRD, register the gate once per CRD.
+	names := []string{}
+	registered := map[string]bool{}
+	w.mu.Lock()
+	for _, gvr := range gvrs {
+		name := gvr.GroupResource().String()
+		if registered[name] {
+			continue
+		}
+		registered[name] = true
+		w.gates[name] = append(w.gates[name], g)
+		names = append(names, name)
+	}
+	w.mu.Unlock()
+
+	CollectorReadyMetric.WithLabelValues(collector).Set(0)
+	klog.Infof("Collector %s waits for CustomResourceDefinition %s", collector, strings.Join(names, " or "))
+	w.sync(names[0])
+}
+
+// sync starts, stops or switches the collectors gated by the named CRD after
+// it changed.
+func (w *crdWatcher) sync(name string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, g := range w.gates[name] {
+		gvr, established := w.preferred(g)
+		if g.cancel != nil && (!established || gvr != g.running) {
+			klog.Infof("CustomResourceDefinition %s is gone or no longer preferred, stopping collector %s", g.running.GroupResource(), g.collector)
+			g.cancel()
+			g.cancel = nil
+		}
+		if established && g.cancel == nil {
+			klog.Infof("CustomResourceDefinition %s is established, starting collector %s on %s", gvr.GroupResource(), g.collector, gvr.GroupVersion())
+			ctx, cancel := context.WithCancel(w.ctx)
+			previous, done := g.done, make(chan struct{})
+			g.cancel, g.running, g.done = cancel, gvr, done
+			go func(g *crdGate) {
+				defer close(done)
+				// Let the previous run drop its state before starting over.
+				<-previous
+				CollectorReadyMetric.WithLabelValues(g.collector).Set(1)
+				g.run(ctx, gvr)
+				CollectorReadyMetric.WithLabelValues(g.collector).Set(0)
+				g.stopped()
+			}(g)
+		}
+	}
+}
+
+// preferred returns the most preferred resource of the gate whose CRD is
+// established, and whether there is one.
+func (w *crdWatcher) preferred(g *crdGate) (schema.GroupVersionResource, bool) {
+	for _, gvr := range g.gvrs {
+		var crd *unstructured.Unstructured
+		if obj, exists, err := w.informer.GetStore().GetByKey(gvr.GroupResource().String()); err == nil && exists {
+			crd, _ = obj.(*unstructured.Unstructured)
+		}
+		if crdEstablished(crd, gvr.Version) {
+			return gvr, true
+		}
+	}
+	return schema.GroupVersionResource{}, false
+}
+
+// crdName returns the name of the CRD, also from a deletion tombstone.
+func crdName(obj interface{}) string {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		return tombstone.Key
+	}
+	if crd, ok := obj.(*unstructured.Unstructured); ok {
+		return crd.GetName()
+	}
+	return ""
+}
+
+// crdEstablished reports whether the CRD exists, is not being deleted, has its
+// Established condition true and serves the given version.
+func crdEstablished(crd *unstructured.Unstructured, version string) bool {
+	if crd == nil || crd.GetDeletionTimestamp() != nil {
+		return false
+	}
+
+	established := false
+	conditions, _, _ := unstructured.NestedSlice(crd.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, _ := c.(map[string]interface{})
+		if condition["type"] == "Established" && condition["status"] == "True" {
+			established = true
+		}
+	}
+	if !established {
+		return false
+	}
+
+	versions, _, _ := unstructured.NestedSlice(crd.Object, "spec", "versions")
+	for _, v := range versions {
+		v, _ := v.(map[string]interface{})
+		if v["name"] == version && v["served"] == true {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,196 @@
+// Copyright (c) 2026 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+func newCRD(name string, version string, established bool) *unstructured.Unstructured {
+	status := "False"
+	if established {
+		status = "True"
+	}
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apiextensions.k8s.io/v1",
+		"kind":       "CustomResourceDefinition",
+		"metadata": map[string]interface{}{
+			"name": name,
+		},
+		"spec": map[string]interface{}{
+			"versions": []interface{}{
+				map[string]interface{}{
+					"name":   version,
+					"served": true,
+				},
+			},
+		},
+		"status": map[string]interface{}{
+			"conditions": []interface{}{
+				map[string]interface{}{
+					"type":   "Established",
+					"status": status,
+				},
+			},
+		},
+	}}
+}
+
+// waitForReady polls CollectorReadyMetric until the collector reports want.
+func waitForReady(t *testing.T, collector string, want float64) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for testutil.ToFloat64(CollectorReadyMetric.WithLabelValues(collector)) != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("collector %s: expected ready %v", collector, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func Test_crdWatcher(t *testing.T) {
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		crdGVR: "CustomResourceDefinitionList",
+	})
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	w := newCRDWatcher(ctx, client)
+
+	runs := make(chan struct{}, 10)
+	stops := make(chan struct{}, 10)
+	w.runWhileEstablished("test-reports", wgPolicyV1alpha2API.gvr(), func(ctx context.Context) {
+		runs <- struct{}{}
+		<-ctx.Done()
+	}, func() {
+		stops <- struct{}{}
+	})
+	waitForReady(t, "test-reports", 0)
+
+	crds := client.Resource(crdGVR)
+	if _, err := crds.Create(ctx, newCRD("policyreports.wgpolicyk8s.io", "v1alpha2", false), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	// Only the version served and established counts.
+	if _, err := crds.Update(ctx, newCRD("policyreports.wgpolicyk8s.io", "v1beta1", true), metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-runs:
+		t.Fatal("collector started before its CRD was established")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if _, err := crds.Update(ctx, newCRD("policyreports.wgpolicyk8s.io", "v1alpha2", true), metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-runs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("collector did not start once its CRD was established")
+	}
+	waitForReady(t, "test-reports", 1)
+
+	if err := crds.Delete(ctx, "policyreports.wgpolicyk8s.io", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-stops:
+	case <-time.After(5 * time.Second):
+		t.Fatal("collector did not stop once its CRD was removed")
+	}
+	waitForReady(t, "test-reports", 0)
+
+	if _, err := crds.Create(ctx, newCRD("policyreports.wgpolicyk8s.io", "v1alpha2", true), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-runs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("collector did not restart once its CRD was installed again")
+	}
+	waitForReady(t, "test-reports", 1)
+}
+
+func Test_crdWatcher_preferred(t *testing.T) {
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		crdGVR: "CustomResourceDefinitionList",
+	})
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	w := newCRDWatcher(ctx, client)
+
+	gvrs := []schema.GroupVersionResource{wgPolicyV1beta1API.gvr(), wgPolicyV1alpha2API.gvr()}
+	runs := make(chan schema.GroupVersionResource, 10)
+	stops := make(chan struct{}, 10)
+	w.runWhileAnyEstablished("test-preferred-reports", gvrs, func(ctx context.Context, gvr schema.GroupVersionResource) {
+		runs <- gvr
+		<-ctx.Done()
+	}, func() {
+		stops <- struct{}{}
+	})
+	w.mu.Lock()
+	if got := len(w.gates["policyreports.wgpolicyk8s.io"]); got != 1 {
+		t.Errorf("expected collector to be gated once on its CRD got %d", got)
+	}
+	w.mu.Unlock()
+
+	expectRun := func(want schema.GroupVersionResource) {
+		t.Helper()
+		select {
+		case got := <-runs:
+			if got != want {
+				t.Errorf("expected collector to run on %v got %v", want, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("collector did not start on %v", want)
+		}
+	}
+	expectStop := func() {
+		t.Helper()
+		select {
+		case <-stops:
+		case <-time.After(5 * time.Second):
+			t.Fatal("collector did not stop")
+		}
+	}
+
+	crds := client.Resource(crdGVR)
+	if _, err := crds.Create(ctx, newCRD("policyreports.wgpolicyk8s.io", "v1alpha2", true), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	expectRun(wgPolicyV1alpha2API.gvr())
+
+	// A CRD of another group leaves the collector running.
+	if _, err := crds.Create(ctx, newCRD("reports.openreports.io", "v1alpha1", true), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-stops:
+		t.Fatal("collector stopped for the CRD of another group")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// A more preferred version served later takes over.
+	if _, err := crds.Update(ctx, newCRD("policyreports.wgpolicyk8s.io", "v1beta1", true), metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	expectStop()
+	expectRun(wgPolicyV1beta1API.gvr())
+	waitForReady(t, "test-preferred-reports", 1)
+
+	if err := crds.Delete(ctx, "policyreports.wgpolicyk8s.io", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	expectStop()
+	waitForReady(t, "test-preferred-reports", 0)
+}
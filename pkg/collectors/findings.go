@@ -0,0 +1,372 @@
+// Copyright (c) 2026 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+// findingStateSavePeriod is how often the state of the findings is saved when
+// it changed.
+const findingStateSavePeriod = 30 * time.Second
+
+var (
+	findingResolutionSecondsMetric = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "policyreport_finding_resolution_seconds",
+			Help:    "Time from when a PolicyReport finding was first seen on a managed cluster to when it disappeared",
+			Buckets: prometheus.ExponentialBuckets(3600, 2, 12),
+		},
+	)
+
+	findingsRaisedTotalMetric = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "policyreport_findings_raised_total",
+			Help: "Total PolicyReport findings raised, counted from the changes between successive versions of each report. A finding with several categories is counted once per category, so summing across category overcounts",
+		},
+		[]string{"severity", "category"},
+	)
+
+	findingsResolvedTotalMetric = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "policyreport_findings_resolved_total",
+			Help: "Total PolicyReport findings resolved, counted from the changes between successive versions of each report. A finding with several categories is counted once per category, so summing across category overcounts",
+		},
+		[]string{"severity", "category"},
+	)
+
+	// findingsRegistry holds the metrics of the findings. They describe the
+	// managed clusters rather than the exporter, so they are served along
+	// with the metrics of the collectors, see Builder.BuildGatherer.
+	findingsRegistry = newFindingsRegistry()
+)
+
+func newFindingsRegistry() *prometheus.Registry {
+	r := prometheus.NewRegistry()
+	r.MustRegister(findingResolutionSecondsMetric, findingsRaisedTotalMetric, findingsResolvedTotalMetric)
+	return r
+}
+
+// findingKey identifies a finding: a policy failing on a managed cluster, named
+// after its cluster namespace.
+type findingKey struct {
+	cluster string
+	policy  string
+}
+
+// findingInfo is what the counters of raised and resolved findings are
+// labelled with. A finding is counted once per category, under an empty one
+// when it has none.
+type findingInfo struct {
+	severity   string
+	categories []string
+}
+
+// inc increments the counter of each category of the finding.
+func (i findingInfo) inc(counter *prometheus.CounterVec) {
+	for _, category := range i.categories {
+		counter.WithLabelValues(i.severity, category).Inc()
+	}
+}
+
+// findingTracker remembers when each finding was first seen and observes how
+// long it stayed open once it disappears. A finding is open while a report of
+// its cluster namespace has a failing result for its policy. The first-seen
+// times can be persisted to a file so that restarts do not reset them.
+//
+// The tracker also counts the findings raised and resolved by each report,
+// diffing each version of the report against the previous one seen. The first
+// version seen of a report created before the tracker started raises nothing,
+// so that restarts and relists do not count the findings already open again.
+// A report that is deleted or no longer watched resolves nothing either: its
+// findings are gone, as when a detached cluster's namespace is deleted, rather
+// than fixed.
+type findingTracker struct {
+	opts resultOptions
+	file string
+	// started is when the tracker was created.
+	started time.Time
+	// watched reports whether the reports of a namespace are watched, every
+	// namespace being watched when nil.
+	watched func(namespace string) bool
+
+	mu sync.Mutex
+	// firstSeen holds when each known finding was first seen.
+	firstSeen map[findingKey]time.Time
+	// reports holds the open findings of each report, by cluster namespace
+	// and UID.
+	reports map[string]map[types.UID]map[string]findingInfo
+	// open counts the reports in which each finding is open.
+	open map[findingKey]int
+	// dirty is set when firstSeen changed since the last save.
+	dirty bool
+}
+
+// findingState is the layout of the state file of a findingTracker.
+type findingState struct {
+	Findings []persistedFinding `json:"findings"`
+}
+
+type persistedFinding struct {
+	Cluster   string    `json:"cluster"`
+	Policy    string    `json:"policy"`
+	FirstSeen time.Time `json:"firstSeen"`
+}
+
+// newFindingTracker returns a findingTracker telling the findings of a report
+// apart with the given options, and persisting its state to the given file
+// unless empty. The state of an existing file is loaded.
+func newFindingTracker(opts resultOptions, file string) (*findingTracker, error) {
+	t := &findingTracker{
+		opts:      opts,
+		file:      file,
+		started:   now(),
+		firstSeen: map[findingKey]time.Time{},
+		reports:   map[string]map[types.UID]map[string]findingInfo{},
+		open:      map[findingKey]int{},
+	}
+	if file == "" {
+		return t, nil
+	}
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	state := findingState{}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	for _, f := range state.Findings {
+		t.firstSeen[findingKey{cluster: f.Cluster, policy: f.Policy}] = f.FirstSeen
+	}
+	klog.Infof("Loaded the first-seen time of %d findings from %s", len(state.Findings), file)
+	return t, nil
+}
+
+// findings returns the policies of the failing results of the report, along
+// with the severity and category of their first failing result.
+func (t *findingTracker) findings(pr *policyReport) map[string]findingInfo {
+	findings := map[string]findingInfo{}
+	for _, r := range pr.results {
+		if r.policy == "" || !t.opts.keeps(r.source) {
+			continue
+		}
+		if _, ok := findings[r.policy]; ok {
+			continue
+		}
+		switch r.result {
+		case "", "fail", "warn", "error":
+			categories := splitCategories(r.category)
+			if len(categories) == 0 {
+				categories = []string{""}
+			}
+			findings[r.policy] = findingInfo{severity: t.opts.severityMapping().severity(r), categories: categories}
+		}
+	}
+	return findings
+}
+
+// observe records the findings of a report, counting those raised and resolved
+// since its previous version, or those of a report created since the tracker
+// started. The findings the report no longer has are resolved by the next call
+// to resolve unless another report of the namespace still has them.
+func (t *findingTracker) observe(namespace string, uid types.UID, pr *policyReport) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// A report new to the tracker, such as one listed after a restart or a
+	// pause of its watch, has no previous version to diff against and raises
+	// nothing unless it was created since.
+	_, tracked := t.reports[namespace][uid]
+	t.setLocked(namespace, uid, t.findings(pr), tracked || pr.CreationTimestamp.After(t.started))
+}
+
+// forget stops tracking a report that is deleted or no longer watched, without
+// counting its findings as resolved. They are resolved by the next call to
+// resolve unless another report of the namespace still has them.
+func (t *findingTracker) forget(namespace string, uid types.UID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.setLocked(namespace, uid, nil, false)
+}
+
+// resolve resolves the known findings of the namespace, of every namespace if
+// empty, that no tracked report has.
+func (t *findingTracker) resolve(namespace string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key, firstSeen := range t.firstSeen {
+		if namespace != "" && key.cluster != namespace {
+			continue
+		}
+		if t.open[key] > 0 {
+			continue
+		}
+		findingResolutionSecondsMetric.Observe(now().Sub(firstSeen).Seconds())
+		delete(t.firstSeen, key)
+		t.dirty = true
+	}
+}
+
+// discard forgets the first-seen times of the findings of the namespaces no
+// longer watched, such as those of a detached cluster or loaded from the state
+// file of another selection, without resolving them. They would stay forever
+// otherwise, as no report of these namespaces resolves them. The findings still
+// open in a tracked report are kept.
+func (t *findingTracker) discard() {
+	if t.watched == nil {
+		return
+	}
+	t.mu.Lock()
+	namespaces := map[string]bool{}
+	for key := range t.firstSeen {
+		namespaces[key.cluster] = true
+	}
+	t.mu.Unlock()
+	for namespace := range namespaces {
+		namespaces[namespace] = t.watched(namespace)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key := range t.firstSeen {
+		if watched, ok := namespaces[key.cluster]; !ok || watched || t.open[key] > 0 {
+			continue
+		}
+		delete(t.firstSeen, key)
+		t.dirty = true
+	}
+}
+
+// firstSeenOf returns when the finding was first seen, or the zero time if it
+// is not open.
+func (t *findingTracker) firstSeenOf(cluster string, policy string) time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.firstSeen[findingKey{cluster: cluster, policy: policy}]
+}
+
+// setLocked replaces the findings of the report, counting those raised and
+// resolved when count is set.
+func (t *findingTracker) setLocked(namespace string, uid types.UID, findings map[string]findingInfo, count bool) {
+	previous := t.reports[namespace][uid]
+	for policy, info := range findings {
+		if _, ok := previous[policy]; ok {
+			continue
+		}
+		key := findingKey{cluster: namespace, policy: policy}
+		t.open[key]++
+		_, known := t.firstSeen[key]
+		if !known {
+			t.firstSeen[key] = now()
+			t.dirty = true
+		}
+		if count {
+			info.inc(findingsRaisedTotalMetric)
+		}
+	}
+	for policy, info := range previous {
+		if _, ok := findings[policy]; ok {
+			continue
+		}
+		key := findingKey{cluster: namespace, policy: policy}
+		if t.open[key]--; t.open[key] <= 0 {
+			delete(t.open, key)
+		}
+		if count {
+			info.inc(findingsResolvedTotalMetric)
+		}
+	}
+
+	if findings == nil {
+		delete(t.reports[namespace], uid)
+		if len(t.reports[namespace]) == 0 {
+			delete(t.reports, namespace)
+		}
+		return
+	}
+	if t.reports[namespace] == nil {
+		t.reports[namespace] = map[types.UID]map[string]findingInfo{}
+	}
+	t.reports[namespace][uid] = findings
+}
+
+// save writes the first-seen times to the state file if they changed since the
+// last save. The file is replaced atomically.
+func (t *findingTracker) save() error {
+	t.mu.Lock()
+	if t.file == "" || !t.dirty {
+		t.mu.Unlock()
+		return nil
+	}
+	state := findingState{Findings: make([]persistedFinding, 0, len(t.firstSeen))}
+	for key, firstSeen := range t.firstSeen {
+		state.Findings = append(state.Findings, persistedFinding{Cluster: key.cluster, Policy: key.policy, FirstSeen: firstSeen})
+	}
+	t.dirty = false
+	t.mu.Unlock()
+
+	sort.Slice(state.Findings, func(i, j int) bool {
+		a, b := state.Findings[i], state.Findings[j]
+		if a.Cluster != b.Cluster {
+			return a.Cluster < b.Cluster
+		}
+		return a.Policy < b.Policy
+	})
+	data, err := json.Marshal(state)
+	if err == nil {
+		err = writeFileAtomic(t.file, data)
+	}
+	if err != nil {
+		t.mu.Lock()
+		t.dirty = true
+		t.mu.Unlock()
+	}
+	return err
+}
+
+// saveEvery discards the findings of the namespaces no longer watched and saves
+// the state at the given period until the context is done, and a last time
+// then.
+func (t *findingTracker) saveEvery(ctx context.Context, period time.Duration) {
+	save := func() {
+		t.discard()
+		if err := t.save(); err != nil {
+			klog.Warningf("Error saving the findings state to %s: %v", t.file, err)
+		}
+	}
+	wait.Until(save, period, ctx.Done())
+	save()
+}
+
+// writeFileAtomic writes the file through a temporary file renamed over it.
+func writeFileAtomic(file string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
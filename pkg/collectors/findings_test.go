@@ -0,0 +1,369 @@
+// Copyright (c) 2026 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	ocinfrav1 "github.com/openshift/api/config/v1"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	mcv1 "open-cluster-management.io/api/cluster/v1"
+	pr "sigs.k8s.io/wg-policy-prototypes/policy-report/pkg/api/wgpolicyk8s.io/v1alpha2"
+)
+
+// resolvedFindings returns the number of resolutions observed so far.
+func resolvedFindings(t *testing.T) uint64 {
+	t.Helper()
+	m := &dto.Metric{}
+	if err := findingResolutionSecondsMetric.Write(m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+func failing(policies ...string) *policyReport {
+	report := &policyReport{}
+	for _, policy := range policies {
+		report.results = append(report.results, reportResult{policy: policy, result: "fail"})
+	}
+	return report
+}
+
+func Test_findingTracker(t *testing.T) {
+	defer func(n func() time.Time) { now = n }(now)
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return start }
+
+	tracker, err := newFindingTracker(newResultOptions([]string{""}, nil), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolved := resolvedFindings(t)
+
+	tracker.observe("cluster-a", "report-1", failing("A", "B"))
+	tracker.observe("cluster-a", "report-2", failing("B"))
+	tracker.observe("cluster-a", "report-3", &policyReport{results: []reportResult{
+		{policy: "C", result: "pass"},
+		{policy: "D", result: "fail", source: "kyverno"},
+	}})
+	tracker.resolve("cluster-a")
+	if got := tracker.firstSeenOf("cluster-a", "A"); !got.Equal(start) {
+		t.Errorf("expected A first seen at %v got %v", start, got)
+	}
+	for _, policy := range []string{"C", "D"} {
+		if got := tracker.firstSeenOf("cluster-a", policy); !got.IsZero() {
+			t.Errorf("expected %s not to be a finding got %v", policy, got)
+		}
+	}
+
+	// B is still open in report-2, A is resolved.
+	now = func() time.Time { return start.Add(2 * time.Hour) }
+	tracker.observe("cluster-a", "report-1", failing())
+	tracker.resolve("cluster-a")
+	if got := tracker.firstSeenOf("cluster-a", "A"); !got.IsZero() {
+		t.Errorf("expected A to be resolved got %v", got)
+	}
+	if got := tracker.firstSeenOf("cluster-a", "B"); !got.Equal(start) {
+		t.Errorf("expected B first seen at %v got %v", start, got)
+	}
+	if got := resolvedFindings(t) - resolved; got != 1 {
+		t.Errorf("expected 1 resolution got %d", got)
+	}
+
+	// A forgotten report resolves its findings on the next resolve only.
+	tracker.forget("cluster-a", "report-2")
+	if got := tracker.firstSeenOf("cluster-a", "B"); !got.Equal(start) {
+		t.Errorf("expected B first seen at %v got %v", start, got)
+	}
+	tracker.resolve("cluster-b")
+	if got := tracker.firstSeenOf("cluster-a", "B"); got.IsZero() {
+		t.Error("expected B not to be resolved by another namespace")
+	}
+	tracker.resolve("")
+	if got := tracker.firstSeenOf("cluster-a", "B"); !got.IsZero() {
+		t.Errorf("expected B to be resolved got %v", got)
+	}
+	if got := resolvedFindings(t) - resolved; got != 2 {
+		t.Errorf("expected 2 resolutions got %d", got)
+	}
+}
+
+func Test_findingTracker_counters(t *testing.T) {
+	raised := func() float64 {
+		return testutil.ToFloat64(findingsRaisedTotalMetric.WithLabelValues("critical", "security"))
+	}
+	resolved := func() float64 {
+		return testutil.ToFloat64(findingsResolvedTotalMetric.WithLabelValues("critical", "security"))
+	}
+	report := func(policies ...string) *policyReport {
+		pr := &policyReport{}
+		for _, policy := range policies {
+			pr.results = append(pr.results, reportResult{
+				policy:     policy,
+				category:   "security",
+				result:     "fail",
+				properties: map[string]string{"total_risk": "4"},
+			})
+		}
+		return pr
+	}
+	raisedBefore, resolvedBefore := raised(), resolved()
+	expect := func(wantRaised float64, wantResolved float64) {
+		t.Helper()
+		if got := raised() - raisedBefore; got != wantRaised {
+			t.Errorf("expected %v raised got %v", wantRaised, got)
+		}
+		if got := resolved() - resolvedBefore; got != wantResolved {
+			t.Errorf("expected %v resolved got %v", wantResolved, got)
+		}
+	}
+
+	tracker, err := newFindingTracker(resultOptions{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first version of a report, such as listed after a restart, has
+	// nothing to be diffed against.
+	tracker.observe("cluster-a", "report-1", report("A", "B"))
+	expect(0, 0)
+
+	// Each version is diffed against the previous one of the same report.
+	tracker.observe("cluster-a", "report-1", report("A", "B"))
+	tracker.observe("cluster-a", "report-1", report("B", "C"))
+	expect(1, 1)
+
+	// Another report raises nothing until its next version.
+	tracker.observe("cluster-a", "report-2", report("B", "D"))
+	expect(1, 1)
+	tracker.observe("cluster-a", "report-2", report("B", "D", "E"))
+	expect(2, 1)
+
+	// A report forgotten then seen again raises nothing.
+	tracker.forget("cluster-a", "report-1")
+	tracker.observe("cluster-a", "report-1", report("B", "C"))
+	expect(2, 1)
+
+	// A report created since the tracker started, such as the first upload
+	// of a newly imported cluster, raises its findings.
+	created := report("F")
+	created.CreationTimestamp = metav1.NewTime(tracker.started.Add(time.Minute))
+	tracker.observe("cluster-b", "report-3", created)
+	expect(3, 1)
+
+	// A deleted report resolves nothing, its findings are gone rather than
+	// fixed.
+	tracker.forget("cluster-a", "report-1")
+	expect(3, 1)
+}
+
+func Test_findingTracker_countersCategories(t *testing.T) {
+	count := func(category string) float64 {
+		return testutil.ToFloat64(findingsRaisedTotalMetric.WithLabelValues("unknown", category))
+	}
+	categories := []string{"performance", "fault_tolerance", "", "performance,fault_tolerance"}
+	before := map[string]float64{}
+	for _, category := range categories {
+		before[category] = count(category)
+	}
+
+	tracker, err := newFindingTracker(resultOptions{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracker.observe("cluster-a", "report-1", &policyReport{})
+	tracker.observe("cluster-a", "report-1", &policyReport{results: []reportResult{
+		{policy: "A", result: "fail", category: "performance, fault_tolerance"},
+		{policy: "B", result: "fail"},
+	}})
+
+	// A finding counts once per category, under an empty one without any.
+	want := map[string]float64{"performance": 1, "fault_tolerance": 1, "": 1, "performance,fault_tolerance": 0}
+	for category, w := range want {
+		if got := count(category) - before[category]; got != w {
+			t.Errorf("category %q: expected %v raised got %v", category, w, got)
+		}
+	}
+}
+
+func Test_findingTracker_save(t *testing.T) {
+	defer func(n func() time.Time) { now = n }(now)
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return start }
+
+	file := filepath.Join(t.TempDir(), "findings.json")
+	tracker, err := newFindingTracker(resultOptions{}, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracker.observe("cluster-a", "report-1", failing("A"))
+	if err := tracker.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A restart keeps the clock of the findings still open.
+	now = func() time.Time { return start.Add(time.Hour) }
+	restarted, err := newFindingTracker(resultOptions{}, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restarted.observe("cluster-a", "report-1", failing("A"))
+	if got := restarted.firstSeenOf("cluster-a", "A"); !got.Equal(start) {
+		t.Errorf("expected A first seen at %v got %v", start, got)
+	}
+	if restarted.dirty {
+		t.Error("expected no change to save")
+	}
+}
+
+func Test_findingTracker_discard(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "findings.json")
+	tracker, err := newFindingTracker(resultOptions{}, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracker.observe("cluster-a", "report-1", failing("A"))
+	tracker.observe("cluster-detached", "report-2", failing("B"))
+	tracker.observe("cluster-open", "report-3", failing("C"))
+	tracker.forget("cluster-detached", "report-2")
+	if err := tracker.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The findings of a namespace no longer watched are dropped without
+	// being resolved, unless a tracked report still has them.
+	restarted, err := newFindingTracker(resultOptions{}, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restarted.watched = func(namespace string) bool { return namespace == "cluster-a" }
+	restarted.observe("cluster-open", "report-3", failing("C"))
+	resolved := resolvedFindings(t)
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	restarted.saveEvery(ctx, time.Hour)
+	if got := resolvedFindings(t) - resolved; got != 0 {
+		t.Errorf("expected no resolution got %d", got)
+	}
+
+	// The state saved when the context is done no longer has them.
+	reloaded, err := newFindingTracker(resultOptions{}, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, finding := range []findingKey{{"cluster-a", "A"}, {"cluster-detached", "B"}, {"cluster-open", "C"}} {
+		got := reloaded.firstSeenOf(finding.cluster, finding.policy)
+		if want := finding.cluster != "cluster-detached"; got.IsZero() == want {
+			t.Errorf("expected %v to be kept: %v", finding, want)
+		}
+	}
+}
+
+func Test_policyReportStore_findings(t *testing.T) {
+	defer func(n func() time.Time) { now = n }(now)
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return start }
+
+	s := scheme.Scheme
+	s.AddKnownTypes(pr.SchemeGroupVersion, &pr.PolicyReport{})
+	s.AddKnownTypes(ocinfrav1.SchemeGroupVersion, &ocinfrav1.ClusterVersion{})
+	s.AddKnownTypes(mcv1.SchemeGroupVersion, &mcv1.ManagedCluster{})
+
+	mc := &mcv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster-a"}}
+	prU := &unstructured.Unstructured{}
+	if err := scheme.Scheme.Convert(&pr.PolicyReport{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-a", Namespace: "cluster-a", UID: "cluster-a-uid"},
+		Results: []*pr.PolicyReportResult{
+			{Policy: "MASTER_DEFINED_AS_MACHINESET", Result: "fail"},
+		},
+	}, prU, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	client := fake.NewSimpleDynamicClient(s, mc, &ocinfrav1.ClusterVersion{ObjectMeta: metav1.ObjectMeta{Name: "version"}})
+	clusters := newSyncedClusterCache(t, client, "", []string{"name"})
+	tracker, err := newFindingTracker(resultOptions{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prStore, store := newTestPolicyReportStore(clusters, policyReportOptions{findings: tracker})
+	want := `policyreport_finding_first_seen_timestamp_seconds{managed_cluster_id="cluster-a",policy="MASTER_DEFINED_AS_MACHINESET",clusterset=""} 1.7672256e+09`
+	raised := testutil.ToFloat64(findingsRaisedTotalMetric.WithLabelValues("unknown", ""))
+
+	if err := prStore.Add(prU); err != nil {
+		t.Fatal(err)
+	}
+	waitForMetrics(t, store, want, true)
+
+	// A finding open in two reports of the cluster is a single series.
+	otherU := &unstructured.Unstructured{}
+	if err := scheme.Scheme.Convert(&pr.PolicyReport{
+		ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "cluster-a", UID: "other-uid"},
+		Results: []*pr.PolicyReportResult{
+			{Policy: "MASTER_DEFINED_AS_MACHINESET", Result: "fail"},
+		},
+	}, otherU, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := prStore.Add(otherU); err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	store.WriteAll(buf)
+	if got := strings.Count(buf.String(), "policyreport_finding_first_seen_timestamp_seconds{"); got != 1 {
+		t.Errorf("expected 1 series for two reports got %d", got)
+	}
+	if err := prStore.Delete(otherU); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reports no longer watched keep their findings open.
+	resolved := resolvedFindings(t)
+	if err := prStore.DropNamespace("cluster-a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := prStore.Drop(); err != nil {
+		t.Fatal(err)
+	}
+	if got := resolvedFindings(t) - resolved; got != 0 {
+		t.Errorf("expected no resolution got %d", got)
+	}
+
+	now = func() time.Time { return start.Add(time.Hour) }
+	if err := prStore.Replace([]interface{}{prU}, ""); err != nil {
+		t.Fatal(err)
+	}
+	waitForMetrics(t, store, want, true)
+
+	// Neither the first list nor the relists diffing the reports against
+	// their previous version raise the finding.
+	if err := prStore.Replace([]interface{}{prU}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := testutil.ToFloat64(findingsRaisedTotalMetric.WithLabelValues("unknown", "")) - raised; got != 0 {
+		t.Errorf("expected no finding raised got %v", got)
+	}
+
+	// Reports gone resolve their findings without counting them as fixed.
+	resolvedTotal := testutil.ToFloat64(findingsResolvedTotalMetric.WithLabelValues("unknown", ""))
+	if err := prStore.ReplaceNamespace("cluster-a", nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	waitForMetrics(t, store, "policyreport_finding_first_seen_timestamp_seconds{", false)
+	if got := resolvedFindings(t) - resolved; got != 1 {
+		t.Errorf("expected 1 resolution got %d", got)
+	}
+	if got := testutil.ToFloat64(findingsResolvedTotalMetric.WithLabelValues("unknown", "")) - resolvedTotal; got != 0 {
+		t.Errorf("expected no finding resolved got %v", got)
+	}
+}
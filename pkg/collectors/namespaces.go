@@ -0,0 +1,191 @@
+// Copyright (c) 2026 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/cache"
+)
+
+// namespaceReflectorThreshold is the number of listed namespaces above which a
+// single cluster-wide reflector filtering namespaces in process is preferred to
+// one reflector, and one watch on the API server, per namespace.
+const namespaceReflectorThreshold = 20
+
+// namespaceFilter selects the namespaces whose objects are collected.
+type namespaceFilter struct {
+	// include selects namespaces. When empty every namespace is selected.
+	include namespaceSet
+	// exclude lists namespaces never selected, even when included.
+	exclude namespaceSet
+}
+
+// namespaceGlob matches the entries that are globs rather than regular
+// expressions: namespace name characters and at least one *.
+var namespaceGlob = regexp.MustCompile(`^[a-z0-9-]*\*[a-z0-9*-]*$`)
+
+// namespaceSet is a set of namespace names and patterns.
+type namespaceSet struct {
+	names    map[string]struct{}
+	patterns []*regexp.Regexp
+}
+
+// newNamespaceFilter returns a filter selecting the given namespaces, all of
+// them if the list holds metav1.NamespaceAll, minus the denied ones. Entries
+// made of namespace name characters and * are globs, where * matches any run
+// of characters, such as open-cluster-management-*. Other entries that are not
+// valid namespace names are regular expressions matching whole namespace
+// names, such as open-cluster-management-.*.
+func newNamespaceFilter(namespaces []string, denylist []string) (namespaceFilter, error) {
+	for _, ns := range namespaces {
+		if ns == metav1.NamespaceAll {
+			namespaces = nil
+			break
+		}
+	}
+	include, err := parseNamespaceSet(namespaces)
+	if err != nil {
+		return namespaceFilter{}, err
+	}
+	exclude, err := parseNamespaceSet(denylist)
+	if err != nil {
+		return namespaceFilter{}, err
+	}
+	return namespaceFilter{include: include, exclude: exclude}, nil
+}
+
+func parseNamespaceSet(entries []string) (namespaceSet, error) {
+	set := namespaceSet{names: map[string]struct{}{}}
+	for _, entry := range entries {
+		if len(validation.IsDNS1123Label(entry)) == 0 {
+			set.names[entry] = struct{}{}
+			continue
+		}
+		pattern := entry
+		if namespaceGlob.MatchString(entry) {
+			parts := strings.Split(entry, "*")
+			for i, part := range parts {
+				parts[i] = regexp.QuoteMeta(part)
+			}
+			pattern = strings.Join(parts, ".*")
+		}
+		p, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return namespaceSet{}, fmt.Errorf("invalid namespace pattern %q: %w", entry, err)
+		}
+		set.patterns = append(set.patterns, p)
+	}
+	return set, nil
+}
+
+func (s namespaceSet) empty() bool {
+	return len(s.names) == 0 && len(s.patterns) == 0
+}
+
+func (s namespaceSet) contains(namespace string) bool {
+	if _, ok := s.names[namespace]; ok {
+		return true
+	}
+	for _, p := range s.patterns {
+		if p.MatchString(namespace) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether the namespace is selected.
+func (f namespaceFilter) matches(namespace string) bool {
+	if f.exclude.contains(namespace) {
+		return false
+	}
+	return f.include.empty() || f.include.contains(namespace)
+}
+
+// perNamespace returns the namespaces to run one reflector each for, or false
+// when the selection calls for a single cluster-wide reflector: all namespaces,
+// patterns, or more namespaces than namespaceReflectorThreshold.
+func (f namespaceFilter) perNamespace() ([]string, bool) {
+	if len(f.include.patterns) > 0 || len(f.include.names) == 0 || len(f.include.names) > namespaceReflectorThreshold {
+		return nil, false
+	}
+	namespaces := []string{}
+	for ns := range f.include.names {
+		if f.matches(ns) {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	sort.Strings(namespaces)
+	return namespaces, true
+}
+
+// filteredStore passes on to its store the objects of the namespaces selected
+// by matches only.
+type filteredStore struct {
+	cache.Store
+	matches func(namespace string) bool
+}
+
+func (s *filteredStore) selected(obj interface{}) bool {
+	o, err := meta.Accessor(obj)
+	return err == nil && s.matches(o.GetNamespace())
+}
+
+func (s *filteredStore) Add(obj interface{}) error {
+	if !s.selected(obj) {
+		return nil
+	}
+	return s.Store.Add(obj)
+}
+
+func (s *filteredStore) Update(obj interface{}) error {
+	if !s.selected(obj) {
+		return nil
+	}
+	return s.Store.Update(obj)
+}
+
+func (s *filteredStore) Delete(obj interface{}) error {
+	if !s.selected(obj) {
+		return nil
+	}
+	return s.Store.Delete(obj)
+}
+
+func (s *filteredStore) Replace(list []interface{}, resourceVersion string) error {
+	selected := []interface{}{}
+	for _, obj := range list {
+		if s.selected(obj) {
+			selected = append(selected, obj)
+		}
+	}
+	return s.Store.Replace(selected, resourceVersion)
+}
+
+// namespaceReplacer is a store shared by reflectors of different namespaces,
+// whose content can be replaced, or dropped when the namespace is no longer
+// watched, one namespace at a time.
+type namespaceReplacer interface {
+	cache.Store
+	ReplaceNamespace(namespace string, list []interface{}, resourceVersion string) error
+	DropNamespace(namespace string) error
+}
+
+// namespaceStore is the view of a namespaceReplacer given to the reflector of a
+// single namespace, so that its relists leave the other namespaces alone.
+type namespaceStore struct {
+	namespaceReplacer
+	namespace string
+}
+
+func (s *namespaceStore) Replace(list []interface{}, resourceVersion string) error {
+	return s.ReplaceNamespace(s.namespace, list, resourceVersion)
+}
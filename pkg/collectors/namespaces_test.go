@@ -0,0 +1,189 @@
+// Copyright (c) 2026 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	ocinfrav1 "github.com/openshift/api/config/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/tools/cache"
+	mcv1 "open-cluster-management.io/api/cluster/v1"
+	pr "sigs.k8s.io/wg-policy-prototypes/policy-report/pkg/api/wgpolicyk8s.io/v1alpha2"
+)
+
+func Test_namespaceFilter(t *testing.T) {
+	many := []string{}
+	for i := 0; i <= namespaceReflectorThreshold; i++ {
+		many = append(many, fmt.Sprintf("cluster-%d", i))
+	}
+
+	tests := []struct {
+		name             string
+		namespaces       []string
+		denylist         []string
+		want             map[string]bool
+		wantPerNamespace []string
+	}{
+		{
+			name:       "all namespaces",
+			namespaces: []string{metav1.NamespaceAll},
+			want:       map[string]bool{"cluster-a": true, "open-cluster-management": true},
+		}, {
+			name:       "all namespaces but denied",
+			namespaces: []string{metav1.NamespaceAll},
+			denylist:   []string{"open-cluster-management-.*", "test-cluster"},
+			want: map[string]bool{
+				"cluster-a":                     true,
+				"open-cluster-management":       true,
+				"open-cluster-management-agent": false,
+				"test-cluster":                  false,
+				"test-cluster-2":                true,
+			},
+		}, {
+			name:             "listed namespaces",
+			namespaces:       []string{"cluster-b", "cluster-a", "cluster-c"},
+			denylist:         []string{"cluster-c"},
+			want:             map[string]bool{"cluster-a": true, "cluster-b": true, "cluster-c": false, "cluster-d": false},
+			wantPerNamespace: []string{"cluster-a", "cluster-b"},
+		}, {
+			name:       "more namespaces than the threshold",
+			namespaces: many,
+			want:       map[string]bool{"cluster-0": true, "cluster-a": false},
+		}, {
+			name:       "patterns",
+			namespaces: []string{"local-cluster", "prod-.*"},
+			denylist:   []string{"prod-test"},
+			want:       map[string]bool{"local-cluster": true, "prod-1": true, "prod-test": false, "dev-1": false, "my-prod-1": false},
+		}, {
+			name:       "globs",
+			namespaces: []string{metav1.NamespaceAll},
+			denylist:   []string{"open-cluster-management-*", "*-test"},
+			want: map[string]bool{
+				"open-cluster-management":         true,
+				"open-cluster-management-":        false,
+				"open-cluster-management-agent":   false,
+				"open-cluster-management--":       false,
+				"open-cluster-management-hub-abc": false,
+				"prod-test":                       false,
+				"prod-test-1":                     true,
+				"cluster-a":                       true,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := newNamespaceFilter(tt.namespaces, tt.denylist)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for ns, want := range tt.want {
+				if got := f.matches(ns); got != want {
+					t.Errorf("namespace %s: expected %v got %v", ns, want, got)
+				}
+			}
+			namespaces, ok := f.perNamespace()
+			if ok != (tt.wantPerNamespace != nil) || !reflect.DeepEqual(namespaces, tt.wantPerNamespace) {
+				t.Errorf("expected per namespace reflectors for %v got %v (%v)", tt.wantPerNamespace, namespaces, ok)
+			}
+		})
+	}
+
+	if _, err := newNamespaceFilter([]string{"cluster-(a"}, nil); err == nil {
+		t.Error("expected an invalid pattern to fail")
+	}
+}
+
+func Test_runNamespacedReflectors(t *testing.T) {
+	s := runtime.NewScheme()
+	s.AddKnownTypes(pr.SchemeGroupVersion, &pr.PolicyReport{}, &pr.PolicyReportList{})
+	s.AddKnownTypes(ocinfrav1.SchemeGroupVersion, &ocinfrav1.ClusterVersion{}, &ocinfrav1.ClusterVersionList{})
+	s.AddKnownTypes(mcv1.SchemeGroupVersion, &mcv1.ManagedCluster{}, &mcv1.ManagedClusterList{})
+
+	objects := []runtime.Object{}
+	for _, name := range []string{"cluster-a", "cluster-b", "cluster-c"} {
+		objects = append(objects,
+			&mcv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+			},
+			&pr.PolicyReport{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: name,
+					UID:       types.UID(name),
+				},
+				Results: []*pr.PolicyReportResult{
+					{
+						Category: "service_availability",
+						Policy:   "MASTER_DEFINED_AS_MACHINESET",
+						Result:   "fail",
+					},
+				},
+			})
+	}
+
+	series := func(name string) string {
+		return fmt.Sprintf(`policyreport_info{managed_cluster_id=%q,category="service_availability",policy="MASTER_DEFINED_AS_MACHINESET",result="fail",severity="unknown",source="",clusterset=""} 1`, name)
+	}
+
+	tests := []struct {
+		name       string
+		namespaces []string
+		denylist   []string
+		present    []string
+		absent     []string
+	}{
+		{
+			name:       "per namespace",
+			namespaces: []string{"cluster-a", "cluster-b"},
+			present:    []string{"cluster-a", "cluster-b"},
+			absent:     []string{"cluster-c"},
+		}, {
+			name:       "cluster-wide",
+			namespaces: []string{"cluster-[ab]"},
+			denylist:   []string{"cluster-b"},
+			present:    []string{"cluster-a"},
+			absent:     []string{"cluster-b", "cluster-c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := newNamespaceFilter(tt.namespaces, tt.denylist)
+			if err != nil {
+				t.Fatal(err)
+			}
+			client := fake.NewSimpleDynamicClient(s, objects...)
+			clusters := newSyncedClusterCache(t, client, "", []string{"name"})
+			prStore, store := newTestPolicyReportStore(clusters, policyReportOptions{})
+
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+			go runNamespacedReflectors(ctx, &unstructured.Unstructured{}, prStore, "", "", filter,
+				func(_ string, _ string, ns string) cache.ListWatch {
+					return createPolicyReportListWatchWithClient(client, wgPolicyV1alpha2API, reportSelectors{}, ns)
+				})
+
+			for _, name := range tt.present {
+				waitForMetrics(t, store, series(name), true)
+			}
+			for _, name := range tt.absent {
+				waitForMetrics(t, store, series(name), false)
+			}
+
+			// A relist of one namespace leaves the others alone.
+			if err := prStore.ReplaceNamespace("cluster-b", nil, ""); err != nil {
+				t.Fatal(err)
+			}
+			waitForMetrics(t, store, series("cluster-a"), true)
+			waitForMetrics(t, store, series("cluster-b"), false)
+		})
+	}
+}
@@ -2,68 +2,192 @@ package collectors
 
 import (
 	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 	"k8s.io/kube-state-metrics/pkg/metric"
-	"sigs.k8s.io/wg-policy-prototypes/policy-report/pkg/api/wgpolicyk8s.io/v1alpha2"
 )
 
 var (
 	descPolicyReportLabelsName    = "policyreport_info"
 	descPolicyReportLabelsHelp    = "Open Cluster Management PolicyReport Info."
-	descPolicyReportDefaultLabels = []string{"managed_cluster_id", "category", "policy", "result", "severity"}
+	descPolicyReportDefaultLabels = []string{"managed_cluster_id", "category", "policy", "result", "severity", "source"}
 
-	policyReportGvr = schema.GroupVersionResource{
-		Group:    "wgpolicyk8s.io",
-		Version:  "v1alpha2",
-		Resource: "policyreports",
-	}
+	descPolicyReportCategoryName   = "policyreport_category_info"
+	descPolicyReportCategoryHelp   = "Individual categories of the policies reported for a managed cluster."
+	descPolicyReportCategoryLabels = []string{"managed_cluster_id", "policy", "category"}
+
+	descPolicyReportClusterIDName   = "policyreport_cluster_id_info"
+	descPolicyReportClusterIDHelp   = "Source of the managed_cluster_id of the cluster reporting a PolicyReport."
+	descPolicyReportClusterIDLabels = []string{"managed_cluster_id", "cluster_name", "cluster_id_source"}
+
+	descPolicyReportSummaryName   = "policyreport_summary"
+	descPolicyReportSummaryHelp   = "Number of PolicyReport results of a managed cluster per result and severity."
+	descPolicyReportSummaryLabels = []string{"managed_cluster_id", "result", "severity"}
+
+	descPolicyReportRiskName   = "policyreport_result_risk"
+	descPolicyReportRiskHelp   = "Insights risk of the results of a policy on a managed cluster per dimension: total_risk, likelihood or impact."
+	descPolicyReportRiskLabels = []string{"managed_cluster_id", "policy", "dimension"}
+
+	descPolicyReportResourcesName   = "policyreport_result_resources"
+	descPolicyReportResourcesHelp   = "Resources affected by the results of a policy on a managed cluster."
+	descPolicyReportResourcesLabels = []string{"managed_cluster_id", "policy", "kind", "namespace", "name"}
+
+	descPolicyReportResourcesDroppedName   = "policyreport_result_resources_dropped"
+	descPolicyReportResourcesDroppedHelp   = "Number of affected resources of a managed cluster left out of policyreport_result_resources by the per-cluster limit."
+	descPolicyReportResourcesDroppedLabels = []string{"managed_cluster_id"}
+
+	descPolicyReportLastUpdatedName   = "policyreport_last_updated_timestamp_seconds"
+	descPolicyReportLastUpdatedHelp   = "Unix timestamp of the last update of the most recently updated PolicyReport of a managed cluster."
+	descPolicyReportLastUpdatedLabels = []string{"managed_cluster_id"}
+
+	descPolicyReportFirstSeenName   = "policyreport_finding_first_seen_timestamp_seconds"
+	descPolicyReportFirstSeenHelp   = "Unix timestamp of when a failing result of a policy was first seen on a managed cluster, kept until no result of the policy fails anymore."
+	descPolicyReportFirstSeenLabels = []string{"managed_cluster_id", "policy"}
+
+	descPolicyReportStaleName = "policyreport_stale"
+	descPolicyReportStaleHelp = "Whether every PolicyReport of a managed cluster was last updated longer ago than the maximum report age."
+
+	// descPolicyReportClusterSetLabel is carried by every series of the collector
+	// so that Insights findings can be filtered per ManagedClusterSet.
+	descPolicyReportClusterSetLabel = "clusterset"
 )
 
-func getPolicyReportMetricFamilies(client dynamic.Interface) []metric.FamilyGenerator {
-	return []metric.FamilyGenerator{
+// policyReportOptions tunes the metric families generated from PolicyReports.
+type policyReportOptions struct {
+	// clusterLabels are the ManagedCluster labels added to policyreport_info.
+	clusterLabels []string
+	// maxReportAge enables policyreport_stale when not zero.
+	maxReportAge time.Duration
+	// maxResultResources enables policyreport_result_resources when not zero
+	// and caps its number of series per managed cluster.
+	maxResultResources int
+	// results tunes how the results of the reports are turned into metrics.
+	results resultOptions
+	// findings enables policyreport_finding_first_seen_timestamp_seconds when
+	// set.
+	findings *findingTracker
+}
+
+// resultOptions tunes how the results of a PolicyReport are turned into
+// metricResults.
+type resultOptions struct {
+	// sources lists the engines whose results are kept, such as insights or
+	// kyverno. Every result is kept when empty.
+	sources map[string]struct{}
+	// severities maps the results to their severity, defaultSeverityMapping
+	// when nil.
+	severities *severityMapping
+}
+
+// newResultOptions returns the resultOptions keeping the results of the given
+// sources, all of them when none is given, and mapping their severity with the
+// given mapping.
+func newResultOptions(sources []string, severities *severityMapping) resultOptions {
+	opts := resultOptions{severities: severities}
+	if len(sources) > 0 {
+		opts.sources = map[string]struct{}{}
+		for _, source := range sources {
+			opts.sources[source] = struct{}{}
+		}
+	}
+	return opts
+}
+
+// severityMapping returns the mapping of the severity of the results.
+func (o resultOptions) severityMapping() severityMapping {
+	if o.severities == nil {
+		return defaultSeverityMapping
+	}
+	return *o.severities
+}
+
+// keeps reports whether the results of the source are kept.
+func (o resultOptions) keeps(source string) bool {
+	if o.sources == nil {
+		return true
+	}
+	_, ok := o.sources[source]
+	return ok
+}
+
+func getPolicyReportMetricFamilies(clusters *clusterCache, opts policyReportOptions) []metric.FamilyGenerator {
+	riskLabelKeys := append(append([]string{}, descPolicyReportRiskLabels...), descPolicyReportClusterSetLabel)
+
+	families := []metric.FamilyGenerator{
 		{
-			Name: descPolicyReportLabelsName,
+			Name: descPolicyReportRiskName,
 			Type: metric.Gauge,
-			Help: descPolicyReportLabelsHelp,
+			Help: descPolicyReportRiskHelp,
 			GenerateFunc: wrapPolicyReportFunc(func(prObj *unstructured.Unstructured) metric.Family {
-				klog.Infof("Getting PolicyReport Info for Cluster Name %s with name %s", prObj.GetNamespace(), prObj.GetName())
-				pr := &v1alpha2.PolicyReport{}
-				err := runtime.DefaultUnstructuredConverter.FromUnstructured(prObj.UnstructuredContent(), &pr)
+				pr, err := decodePolicyReport(prObj)
 				if err != nil {
 					klog.Infof("Error unstructuring PolicyReport ")
 					return metric.Family{Metrics: []*metric.Metric{}}
 				}
-				_, errPR := client.Resource(policyReportGvr).Namespace(pr.GetNamespace()).Get(context.TODO(), pr.GetName(), metav1.GetOptions{})
-				if errPR != nil {
-					klog.Infof("PolicyReport %s not found, err: %s", pr.GetName(), errPR)
-					return metric.Family{Metrics: []*metric.Metric{}}
-				}
 				clusterName := pr.GetNamespace()
-				clusterId := getClusterID(client, clusterName)
+				clusterId := clusters.clusterID(clusterName)
+				clusterSet := clusters.clusterLabels(clusterName)[clusterSetLabel]
 
 				f := metric.Family{}
 
-				for result, val := range getResults(clusterId, pr) {
+				for risk, val := range getRisks(clusterId, pr, opts.results) {
 					f.Metrics = append(f.Metrics, &metric.Metric{
-						LabelKeys:   descPolicyReportDefaultLabels,
-						LabelValues: result.values(),
-						Value:       float64(val),
+						LabelKeys:   riskLabelKeys,
+						LabelValues: []string{clusterId, risk.policy, risk.dimension, clusterSet},
+						Value:       val,
 					})
 				}
-
-				klog.Infof("Returning %v", string(f.ByteSlice()))
 				return f
 			}),
 		},
 	}
+
+	return families
+}
+
+// clusterLabelNames returns the allow-listed ManagedCluster labels along with
+// their Prometheus label names. A label whose name collides with an earlier one
+// once sanitized is dropped.
+func clusterLabelNames(labels []string) ([]string, []string) {
+	kept := []string{}
+	keys := []string{}
+	seen := map[string]string{}
+	for _, l := range labels {
+		key := sanitizeLabelName(l)
+		if other, ok := seen[key]; ok {
+			if other != l {
+				klog.Warningf("ManagedCluster label %s is ignored, its metric label %s is already used by %s", l, key, other)
+			}
+			continue
+		}
+		seen[key] = l
+		kept = append(kept, l)
+		keys = append(keys, key)
+	}
+	return kept, keys
+}
+
+// clusterLabelValues returns the values of the given labels, "" when unset.
+func clusterLabelValues(clusterLabels map[string]string, labels []string) []string {
+	values := make([]string, len(labels))
+	for i, l := range labels {
+		values[i] = clusterLabels[l]
+	}
+	return values
 }
 
 func wrapPolicyReportFunc(f func(*unstructured.Unstructured) metric.Family) func(interface{}) *metric.Family {
@@ -81,13 +205,39 @@ func wrapPolicyReportFunc(f func(*unstructured.Unstructured) metric.Family) func
 	}
 }
 
-func createPolicyReportListWatchWithClient(client dynamic.Interface, ns string) cache.ListWatch {
+// reportSelectors restricts the reports listed and watched to those matching a
+// label selector and a field selector. Empty selectors match every report.
+type reportSelectors struct {
+	label string
+	field string
+}
+
+// newReportSelectors returns the reportSelectors of the given label and field
+// selectors, or an error if either does not parse.
+func newReportSelectors(label string, field string) (reportSelectors, error) {
+	if _, err := labels.Parse(label); err != nil {
+		return reportSelectors{}, fmt.Errorf("invalid label selector %q: %w", label, err)
+	}
+	if _, err := fields.ParseSelector(field); err != nil {
+		return reportSelectors{}, fmt.Errorf("invalid field selector %q: %w", field, err)
+	}
+	return reportSelectors{label: label, field: field}, nil
+}
+
+// apply sets the selectors on the options of a List or Watch call.
+func (s reportSelectors) apply(opts metav1.ListOptions) metav1.ListOptions {
+	opts.LabelSelector = s.label
+	opts.FieldSelector = s.field
+	return opts
+}
+
+func createPolicyReportListWatchWithClient(client dynamic.Interface, api reportAPI, selectors reportSelectors, ns string) cache.ListWatch {
 	return cache.ListWatch{
 		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
-			return client.Resource(policyReportGvr).Namespace(ns).List(context.TODO(), opts)
+			return client.Resource(api.gvr()).Namespace(ns).List(context.TODO(), selectors.apply(opts))
 		},
 		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
-			return client.Resource(policyReportGvr).Namespace(ns).Watch(context.TODO(), opts)
+			return client.Resource(api.gvr()).Namespace(ns).Watch(context.TODO(), selectors.apply(opts))
 		},
 	}
 }
@@ -98,6 +248,7 @@ type metricResult struct {
 	policy    string
 	result    string
 	severity  string
+	source    string
 }
 
 func (mr metricResult) values() []string {
@@ -107,50 +258,243 @@ func (mr metricResult) values() []string {
 		mr.policy,
 		mr.result,
 		mr.severity,
+		mr.source,
 	}
 }
 
 // getResults extracts the metrics information from the results in the PolicyReport.
 // Since multiple results can share the same name & labels, a count for each is returned.
-func getResults(clusterID string, pr *v1alpha2.PolicyReport) map[metricResult]int {
+func getResults(clusterID string, pr *policyReport, opts resultOptions) map[metricResult]int {
 	results := make(map[metricResult]int)
 
 	if clusterID == "" {
 		return results
 	}
 
-	for _, reportResult := range pr.Results {
-		var severity string
+	severities := opts.severityMapping()
+
+	for _, reportResult := range pr.results {
+		if !opts.keeps(reportResult.source) {
+			continue
+		}
 
 		result := "fail"
 
-		if reportResult.Result != "" {
-			result = string(reportResult.Result)
+		if reportResult.result != "" {
+			result = reportResult.result
 		}
 
-		switch risk := reportResult.Properties["total_risk"]; risk {
-		case "4":
-			severity = "critical"
-		case "3":
-			severity = "important"
-		case "2":
-			severity = "moderate"
-		case "1":
-			severity = "low"
-		default:
-			severity = "unknown"
-		}
+		severity := severities.severity(reportResult)
 
-		if reportResult.Policy != "" {
+		if reportResult.policy != "" {
 			results[metricResult{
 				clusterID: clusterID,
-				category:  reportResult.Category,
-				policy:    reportResult.Policy,
+				category:  reportResult.category,
+				policy:    reportResult.policy,
 				result:    result,
 				severity:  severity,
+				source:    reportResult.source,
 			}] += 1
 		}
 	}
 
 	return results
 }
+
+// riskDimensions are the result properties in which Insights rates the risk of
+// a result.
+var riskDimensions = []string{"total_risk", "likelihood", "impact"}
+
+type metricCategory struct {
+	policy   string
+	category string
+}
+
+// getCategories returns the individual categories of the policies of the
+// PolicyReports of a cluster, splitting the comma-joined categories of Insights
+// such as "openshift,configuration,service_availability". A category of a
+// policy reported several times is returned once.
+func getCategories(clusterID string, reports []*policyReport, opts resultOptions) []metricCategory {
+	if clusterID == "" {
+		return nil
+	}
+
+	seen := map[metricCategory]struct{}{}
+	categories := []metricCategory{}
+	for _, pr := range reports {
+		for _, reportResult := range pr.results {
+			if reportResult.policy == "" || !opts.keeps(reportResult.source) {
+				continue
+			}
+			for _, category := range splitCategories(reportResult.category) {
+				c := metricCategory{policy: reportResult.policy, category: category}
+				if _, ok := seen[c]; ok {
+					continue
+				}
+				seen[c] = struct{}{}
+				categories = append(categories, c)
+			}
+		}
+	}
+	return categories
+}
+
+// splitCategories returns the categories of a comma-separated category list,
+// such as the one Insights writes, trimmed and without the empty ones.
+func splitCategories(category string) []string {
+	categories := []string{}
+	for _, c := range strings.Split(category, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			categories = append(categories, c)
+		}
+	}
+	return categories
+}
+
+type metricRisk struct {
+	policy    string
+	dimension string
+}
+
+// getRisks returns the numeric risk dimensions of the results of the report per
+// policy. A policy with several results is rated by its riskiest result, and a
+// property that is not a number is ignored.
+func getRisks(clusterID string, pr *policyReport, opts resultOptions) map[metricRisk]float64 {
+	risks := make(map[metricRisk]float64)
+
+	if clusterID == "" {
+		return risks
+	}
+
+	for _, reportResult := range pr.results {
+		if reportResult.policy == "" || !opts.keeps(reportResult.source) {
+			continue
+		}
+		for _, dimension := range riskDimensions {
+			value, ok := reportResult.properties[dimension]
+			if !ok {
+				continue
+			}
+			v, err := strconv.ParseFloat(value, 64)
+			if err == nil && (math.IsNaN(v) || math.IsInf(v, 0)) {
+				err = fmt.Errorf("not a finite number")
+			}
+			if err != nil {
+				klog.V(2).Infof("Ignoring %s %q of policy %s: %v", dimension, value, reportResult.policy, err)
+				continue
+			}
+			key := metricRisk{policy: reportResult.policy, dimension: dimension}
+			if current, ok := risks[key]; !ok || v > current {
+				risks[key] = v
+			}
+		}
+	}
+
+	return risks
+}
+
+type metricResource struct {
+	policy    string
+	kind      string
+	namespace string
+	name      string
+}
+
+// getResultResources returns up to limit distinct resources affected by the
+// results of the PolicyReports of a cluster, sorted by policy, kind, namespace
+// and name so that the same ones are kept from one update to the next, along
+// with the number of resources left out. The limit caps the series of the
+// cluster however many reports its namespace holds.
+func getResultResources(clusterID string, reports []*policyReport, opts resultOptions, limit int) ([]metricResource, int) {
+	if clusterID == "" {
+		return nil, 0
+	}
+
+	seen := map[metricResource]struct{}{}
+	resources := []metricResource{}
+	for _, pr := range reports {
+		for _, reportResult := range pr.results {
+			if reportResult.policy == "" || !opts.keeps(reportResult.source) {
+				continue
+			}
+			for _, ref := range reportResult.resources {
+				r := metricResource{policy: reportResult.policy, kind: ref.Kind, namespace: ref.Namespace, name: ref.Name}
+				if _, ok := seen[r]; ok {
+					continue
+				}
+				seen[r] = struct{}{}
+				resources = append(resources, r)
+			}
+		}
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		a, b := resources[i], resources[j]
+		if a.policy != b.policy {
+			return a.policy < b.policy
+		}
+		if a.kind != b.kind {
+			return a.kind < b.kind
+		}
+		if a.namespace != b.namespace {
+			return a.namespace < b.namespace
+		}
+		return a.name < b.name
+	})
+
+	if len(resources) > limit {
+		return resources[:limit], len(resources) - limit
+	}
+	return resources, 0
+}
+
+type metricSummary struct {
+	result   string
+	severity string
+}
+
+// getSummary totals the results of the PolicyReports of a cluster per result
+// and severity. A report that only carries a Summary is totalled from it, with
+// an unknown severity since the Summary does not break the counts down any
+// further, unless only some sources are kept.
+func getSummary(clusterID string, reports []*policyReport, opts resultOptions) map[metricSummary]int {
+	summary := make(map[metricSummary]int)
+
+	if clusterID == "" {
+		return summary
+	}
+
+	for _, pr := range reports {
+		for result, val := range getResults(clusterID, pr, opts) {
+			summary[metricSummary{result: result.result, severity: result.severity}] += val
+		}
+
+		// The summary of a report without results tells no source, it is only
+		// totalled when every source is kept.
+		if len(pr.results) == 0 && opts.sources == nil {
+			for result, val := range pr.summary {
+				if val > 0 {
+					summary[metricSummary{result: result, severity: opts.severityMapping().Default}] += val
+				}
+			}
+		}
+	}
+
+	return summary
+}
+
+// lastUpdated returns the newest of the creation, managed fields and result
+// timestamps of the PolicyReport, or the zero time if it carries none.
+func lastUpdated(pr *policyReport) time.Time {
+	updated := pr.GetCreationTimestamp().Time
+	for _, f := range pr.GetManagedFields() {
+		if f.Time != nil && f.Time.After(updated) {
+			updated = f.Time.Time
+		}
+	}
+	for _, reportResult := range pr.results {
+		if reportResult.timestamp.After(updated) {
+			updated = reportResult.timestamp
+		}
+	}
+	return updated
+}
@@ -10,11 +10,11 @@ import (
 	"k8s.io/klog/v2"
 )
 
-func createPolicyReportListWatch(apiserver string, kubeconfig string, ns string) cache.ListWatch {
+func createPolicyReportListWatch(apiserver string, kubeconfig string, api reportAPI, selectors reportSelectors, ns string) cache.ListWatch {
 	config, err := clientcmd.BuildConfigFromFlags(apiserver, kubeconfig)
 	if err != nil {
 		klog.Fatalf("cannot create Dynamic client: %v", err)
 	}
 	client := dynamic.NewForConfigOrDie(config)
-	return createPolicyReportListWatchWithClient(client, ns)
+	return createPolicyReportListWatchWithClient(client, api, selectors, ns)
 }
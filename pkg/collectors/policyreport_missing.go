@@ -0,0 +1,124 @@
+// Copyright (c) 2026 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"k8s.io/kube-state-metrics/pkg/metric"
+	metricsstore "k8s.io/kube-state-metrics/pkg/metrics_store"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+var (
+	descPolicyReportMissingName   = "policyreport_missing"
+	descPolicyReportMissingHelp   = "Whether an available managed cluster has had no PolicyReport for longer than the grace period."
+	descPolicyReportMissingLabels = []string{"managed_cluster_id", "cluster_name"}
+)
+
+// missingReportResyncPeriod is how often policyreport_missing is re-evaluated
+// for every ManagedCluster. It changes with time alone once the grace period of
+// a cluster is over and when the cluster gets or loses its PolicyReports.
+const missingReportResyncPeriod = time.Minute
+
+// getMissingReportMetricFamilies returns the families generated from
+// ManagedClusters. A cluster is missing its report when it is available, and
+// has had no report, for longer than gracePeriod. Clusters only count as
+// missing reports from started on, so that a restart gives the reflectors the
+// same grace period to list the existing reports. Clusters whose namespace is
+// not collected are left out.
+func getMissingReportMetricFamilies(clusters *clusterCache, reports *policyReportStore, namespaces namespaceFilter, gracePeriod time.Duration, started time.Time) []metric.FamilyGenerator {
+	missingLabelKeys := append(append([]string{}, descPolicyReportMissingLabels...), descPolicyReportClusterSetLabel)
+
+	return []metric.FamilyGenerator{
+		{
+			Name: descPolicyReportMissingName,
+			Type: metric.Gauge,
+			Help: descPolicyReportMissingHelp,
+			GenerateFunc: wrapManagedClusterFunc(func(mc *clusterv1.ManagedCluster) metric.Family {
+				if !namespaces.matches(mc.GetName()) {
+					return metric.Family{Metrics: []*metric.Metric{}}
+				}
+				available := meta.FindStatusCondition(mc.Status.Conditions, clusterv1.ManagedClusterConditionAvailable)
+				if available == nil || available.Status != "True" {
+					return metric.Family{Metrics: []*metric.Metric{}}
+				}
+
+				missing := 0.0
+				if emptied, ok := reports.noReportSince(mc.GetName()); ok {
+					since := started
+					for _, t := range []time.Time{available.LastTransitionTime.Time, emptied} {
+						if t.After(since) {
+							since = t
+						}
+					}
+					if now().Sub(since) > gracePeriod {
+						missing = 1
+					}
+				}
+
+				return metric.Family{
+					Metrics: []*metric.Metric{
+						{
+							LabelKeys:   missingLabelKeys,
+							LabelValues: []string{clusters.clusterID(mc.GetName()), mc.GetName(), mc.GetLabels()[clusterSetLabel]},
+							Value:       missing,
+						},
+					},
+				}
+			}),
+		},
+	}
+}
+
+func wrapManagedClusterFunc(f func(*clusterv1.ManagedCluster) metric.Family) func(interface{}) *metric.Family {
+	return func(obj interface{}) *metric.Family {
+		mc := &clusterv1.ManagedCluster{}
+		err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.(*unstructured.Unstructured).UnstructuredContent(), &mc)
+		if err != nil {
+			klog.Warningf("Error unmarshal ManagedCluster object %v", err)
+			return &metric.Family{Metrics: []*metric.Metric{}}
+		}
+
+		metricFamily := f(mc)
+
+		for _, m := range metricFamily.Metrics {
+			m.LabelKeys = append([]string{}, m.LabelKeys...)
+			m.LabelValues = append([]string{}, m.LabelValues...)
+		}
+
+		return &metricFamily
+	}
+}
+
+// feedManagedClusters keeps the store in sync with the ManagedClusters of the
+// cache and regenerates all of them at the given period until the context is
+// done.
+func feedManagedClusters(ctx context.Context, clusters *clusterCache, store *metricsstore.MetricsStore, period time.Duration) {
+	if _, err := clusters.mcInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { _ = store.Add(obj) },
+		UpdateFunc: func(_, obj interface{}) { _ = store.Update(obj) },
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			_ = store.Delete(obj)
+		},
+	}); err != nil {
+		klog.Fatalf("cannot watch ManagedClusters: %v", err)
+	}
+
+	go wait.Until(func() {
+		for _, obj := range clusters.mcInformer.GetStore().List() {
+			_ = store.Update(obj)
+		}
+	}, period, ctx.Done())
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2026 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"testing"
+	"time"
+
+	ocinfrav1 "github.com/openshift/api/config/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/kube-state-metrics/pkg/metric"
+	metricsstore "k8s.io/kube-state-metrics/pkg/metrics_store"
+	mcv1 "open-cluster-management.io/api/cluster/v1"
+	pr "sigs.k8s.io/wg-policy-prototypes/policy-report/pkg/api/wgpolicyk8s.io/v1alpha2"
+)
+
+func Test_getMissingReportMetricFamilies(t *testing.T) {
+	s := scheme.Scheme
+
+	s.AddKnownTypes(pr.SchemeGroupVersion, &pr.PolicyReport{})
+	s.AddKnownTypes(ocinfrav1.SchemeGroupVersion, &ocinfrav1.ClusterVersion{})
+	s.AddKnownTypes(mcv1.SchemeGroupVersion, &mcv1.ManagedCluster{})
+	version := &ocinfrav1.ClusterVersion{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "version",
+		},
+	}
+
+	started := time.Unix(1700000000, 0)
+	defer func(n func() time.Time) { now = n }(now)
+	now = func() time.Time { return started.Add(48 * time.Hour) }
+
+	newCluster := func(name string, status metav1.ConditionStatus, since time.Time) *unstructured.Unstructured {
+		mc := &mcv1.ManagedCluster{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       "ManagedCluster",
+				APIVersion: mcv1.SchemeGroupVersion.String(),
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+				Labels: map[string]string{
+					"cluster.open-cluster-management.io/clusterset": "team-a",
+				},
+			},
+			Status: mcv1.ManagedClusterStatus{
+				Conditions: []metav1.Condition{
+					{
+						Type:               mcv1.ManagedClusterConditionAvailable,
+						Status:             status,
+						LastTransitionTime: metav1.NewTime(since),
+					},
+				},
+				ClusterClaims: []mcv1.ManagedClusterClaim{
+					{
+						Name:  "id.openshift.io",
+						Value: name + "_id",
+					},
+				},
+			},
+		}
+		mcU := &unstructured.Unstructured{}
+		if err := scheme.Scheme.Convert(mc, mcU, nil); err != nil {
+			t.Error(err)
+		}
+		return mcU
+	}
+	silent := newCluster("silent", metav1.ConditionTrue, started)
+	reporting := newCluster("reporting", metav1.ConditionTrue, started)
+	recent := newCluster("recent", metav1.ConditionTrue, started.Add(47*time.Hour))
+	unavailable := newCluster("unavailable", metav1.ConditionUnknown, started)
+
+	report := &pr.PolicyReport{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "reporting",
+			Namespace: "reporting",
+			UID:       "reporting-uid",
+		},
+	}
+	reportU := &unstructured.Unstructured{}
+	if err := scheme.Scheme.Convert(report, reportU, nil); err != nil {
+		t.Error(err)
+	}
+
+	client := fake.NewSimpleDynamicClient(s, version, silent, reporting, recent, unavailable, reportU)
+	clusters := newSyncedClusterCache(t, client, "", nil)
+	reports := newPolicyReportStore(metricsstore.NewMetricsStore(nil, func(interface{}) []metricsstore.FamilyByteSlicer { return nil }), clusters)
+	if err := reports.Add(reportU); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []generateMetricsTestCase{
+		{
+			Obj:  silent,
+			Want: `policyreport_missing{managed_cluster_id="silent_id",cluster_name="silent",clusterset="team-a"} 1`,
+		}, {
+			Obj:  reporting,
+			Want: `policyreport_missing{managed_cluster_id="reporting_id",cluster_name="reporting",clusterset="team-a"} 0`,
+		}, {
+			Obj:  recent,
+			Want: `policyreport_missing{managed_cluster_id="recent_id",cluster_name="recent",clusterset="team-a"} 0`,
+		}, {
+			Obj:  unavailable,
+			Want: "",
+		},
+	}
+	all, err := newNamespaceFilter([]string{metav1.NamespaceAll}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, c := range tests {
+		c.Func = metric.ComposeMetricGenFuncs(getMissingReportMetricFamilies(clusters, reports, all, 24*time.Hour, started))
+		if err := c.run(); err != nil {
+			t.Errorf("unexpected collecting result in %v run:\n%s", i, err)
+		}
+	}
+
+	// Once its last report is gone, the cluster gets a new grace period.
+	if err := reports.Delete(reportU); err != nil {
+		t.Fatal(err)
+	}
+	c := generateMetricsTestCase{
+		Obj:  reporting,
+		Want: `policyreport_missing{managed_cluster_id="reporting_id",cluster_name="reporting",clusterset="team-a"} 0`,
+		Func: metric.ComposeMetricGenFuncs(getMissingReportMetricFamilies(clusters, reports, all, 24*time.Hour, started)),
+	}
+	if err := c.run(); err != nil {
+		t.Errorf("unexpected collecting result after deleting the report:\n%s", err)
+	}
+
+	// Clusters whose namespace is not collected cannot miss their report.
+	denied, err := newNamespaceFilter([]string{metav1.NamespaceAll}, []string{"sil.*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c = generateMetricsTestCase{
+		Obj:  silent,
+		Want: "",
+		Func: metric.ComposeMetricGenFuncs(getMissingReportMetricFamilies(clusters, reports, denied, 24*time.Hour, started)),
+	}
+	if err := c.run(); err != nil {
+		t.Errorf("unexpected collecting result for a denied cluster namespace:\n%s", err)
+	}
+}
@@ -0,0 +1,346 @@
+// Copyright (c) 2026 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	metricsstore "k8s.io/kube-state-metrics/pkg/metrics_store"
+)
+
+// policyReportStore wraps the MetricsStore fed by the PolicyReport reflectors.
+// The metrics of a report depend on the identity and labels of its cluster,
+// which may be missing or change after the report was seen (an ID claim added
+// during import, a fallback ID replaced by a preferred one, a relabelled
+// cluster), so the store keeps the reports of each cluster and regenerates
+// their metrics when the cluster changes. The families describing a cluster as
+// a whole are generated from all the reports of its namespace, fed as
+// clusterReports to a second MetricsStore.
+type policyReportStore struct {
+	*metricsstore.MetricsStore
+
+	clusters *clusterCache
+	// perCluster receives the clusterReports of each cluster namespace, when
+	// set.
+	perCluster *metricsstore.MetricsStore
+	// findings tracks the findings of the reports, when set.
+	findings *findingTracker
+
+	mu sync.Mutex
+	// reports holds the tracked reports indexed by cluster namespace and UID.
+	reports map[string]map[types.UID]interface{}
+	// emptied holds when each cluster namespace lost its last report.
+	emptied map[string]time.Time
+}
+
+// newPolicyReportStore returns a policyReportStore writing into the given
+// MetricsStore and re-emitting reports when their clusters change.
+func newPolicyReportStore(store *metricsstore.MetricsStore, clusters *clusterCache) *policyReportStore {
+	s := &policyReportStore{
+		MetricsStore: store,
+		clusters:     clusters,
+		reports:      map[string]map[types.UID]interface{}{},
+		emptied:      map[string]time.Time{},
+	}
+	clusters.onClusterChange(s.clusterChanged)
+	return s
+}
+
+// Add generates the metrics of the report and tracks it.
+func (s *policyReportStore) Add(obj interface{}) error {
+	o, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.add(obj); err != nil {
+		return err
+	}
+	return s.updateCluster(o.GetNamespace())
+}
+
+// Update regenerates the metrics of the report.
+func (s *policyReportStore) Update(obj interface{}) error {
+	return s.Add(obj)
+}
+
+// Delete removes the metrics of the report and stops tracking it.
+func (s *policyReportStore) Delete(obj interface{}) error {
+	o, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.untrack(o.GetNamespace(), o.GetUID())
+	if s.findings != nil {
+		s.findings.forget(o.GetNamespace(), o.GetUID())
+		s.findings.resolve(o.GetNamespace())
+	}
+	if err := s.MetricsStore.Delete(obj); err != nil {
+		return err
+	}
+	return s.updateCluster(o.GetNamespace())
+}
+
+// Replace drops every tracked report and adds the given list. The findings no
+// report of the list has are resolved.
+func (s *policyReportStore) Replace(list []interface{}, _ string) error {
+	return s.replaceAndResolve("", metav1.NamespaceAll, list)
+}
+
+// Drop drops every tracked report without resolving their findings, for when
+// the reports are no longer watched rather than gone.
+func (s *policyReportStore) Drop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.replace("", metav1.NamespaceAll, nil)
+}
+
+// ReplaceNamespace drops the tracked reports of the namespace and adds the given
+// list, leaving the other namespaces alone. The findings of the namespace no
+// report of the list has are resolved.
+func (s *policyReportStore) ReplaceNamespace(namespace string, list []interface{}, _ string) error {
+	return s.replaceAndResolve("", namespace, list)
+}
+
+// DropNamespace drops the tracked reports of the namespace without resolving
+// their findings, for when the namespace is no longer watched.
+func (s *policyReportStore) DropNamespace(namespace string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.replace("", namespace, nil)
+}
+
+// noReportSince reports whether the given cluster namespace has no report, and
+// since when. The time is zero if the namespace never had a report.
+func (s *policyReportStore) noReportSince(namespace string) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.reports[namespace]) > 0 {
+		return time.Time{}, false
+	}
+	return s.emptied[namespace], true
+}
+
+// Resync regenerates the metrics of every cluster namespace, leaving those of
+// the reports, which do not depend on time, alone.
+func (s *policyReportStore) Resync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for namespace := range s.reports {
+		if err := s.updateCluster(namespace); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resyncEvery calls Resync at the given period until the context is done, so
+// that time-dependent metrics such as policyreport_stale move on even when no
+// report changes.
+func (s *policyReportStore) resyncEvery(ctx context.Context, period time.Duration) {
+	wait.Until(func() {
+		if err := s.Resync(); err != nil {
+			klog.Warningf("Error regenerating PolicyReport metrics: %v", err)
+		}
+	}, period, ctx.Done())
+}
+
+// replaceAndResolve is replace for a list, resolving the findings of the
+// namespace, of every namespace if empty, that no tracked report has.
+func (s *policyReportStore) replaceAndResolve(group string, namespace string, list []interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.replace(group, namespace, list); err != nil {
+		return err
+	}
+	if s.findings != nil {
+		s.findings.resolve(namespace)
+	}
+	return nil
+}
+
+// replace drops the tracked reports of the API group in the namespace, of
+// every group or namespace if empty, and adds the given list. The findings of
+// the reports missing from the list are forgotten.
+func (s *policyReportStore) replace(group string, namespace string, list []interface{}) error {
+	previous := map[string]map[types.UID]interface{}{}
+	for ns, reports := range s.reports {
+		if namespace != metav1.NamespaceAll && ns != namespace {
+			continue
+		}
+		for uid, obj := range reports {
+			if group != "" && reportGroup(obj) != group {
+				continue
+			}
+			if previous[ns] == nil {
+				previous[ns] = map[types.UID]interface{}{}
+			}
+			previous[ns][uid] = obj
+		}
+	}
+	s.untrackFindings(previous, list)
+
+	namespaces := map[string]struct{}{}
+	for ns, reports := range previous {
+		namespaces[ns] = struct{}{}
+		for uid, obj := range reports {
+			s.untrack(ns, uid)
+			if err := s.MetricsStore.Delete(obj); err != nil {
+				return err
+			}
+		}
+	}
+	for _, obj := range list {
+		if err := s.add(obj); err != nil {
+			return err
+		}
+		if o, err := meta.Accessor(obj); err == nil {
+			namespaces[o.GetNamespace()] = struct{}{}
+		}
+	}
+	for ns := range namespaces {
+		if err := s.updateCluster(ns); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// untrackFindings forgets the findings of the given reports missing from the
+// list. The reports of the list keep theirs, so that adding them again diffs
+// them against their previous version.
+func (s *policyReportStore) untrackFindings(reports map[string]map[types.UID]interface{}, list []interface{}) {
+	if s.findings == nil {
+		return
+	}
+	listed := map[types.UID]struct{}{}
+	for _, obj := range list {
+		if o, err := meta.Accessor(obj); err == nil {
+			listed[o.GetUID()] = struct{}{}
+		}
+	}
+	for namespace, uids := range reports {
+		for uid := range uids {
+			if _, ok := listed[uid]; ok {
+				continue
+			}
+			s.findings.forget(namespace, uid)
+		}
+	}
+}
+
+func (s *policyReportStore) add(obj interface{}) error {
+	o, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+
+	if s.reports[o.GetNamespace()] == nil {
+		s.reports[o.GetNamespace()] = map[types.UID]interface{}{}
+	}
+	s.reports[o.GetNamespace()][o.GetUID()] = obj
+	// The findings are observed first, their metrics depend on them.
+	if prObj, ok := obj.(*unstructured.Unstructured); ok && s.findings != nil {
+		if pr, err := decodePolicyReport(prObj); err == nil {
+			s.findings.observe(o.GetNamespace(), o.GetUID(), pr)
+		} else {
+			klog.Warningf("Error decoding PolicyReport %s/%s, not tracking its findings: %v", o.GetNamespace(), o.GetName(), err)
+		}
+	}
+	return s.MetricsStore.Add(obj)
+}
+
+// reportGroup returns the API group of the report.
+func reportGroup(obj interface{}) string {
+	if o, ok := obj.(runtime.Object); ok {
+		return o.GetObjectKind().GroupVersionKind().Group
+	}
+	return ""
+}
+
+func (s *policyReportStore) untrack(namespace string, uid types.UID) {
+	if _, ok := s.reports[namespace][uid]; !ok {
+		return
+	}
+	delete(s.reports[namespace], uid)
+	if len(s.reports[namespace]) == 0 {
+		delete(s.reports, namespace)
+		s.emptied[namespace] = now()
+	}
+}
+
+// clusterChanged regenerates the metrics of the reports of the given cluster.
+func (s *policyReportStore) clusterChanged(clusterName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, obj := range s.reports[clusterName] {
+		klog.Infof("Cluster %s changed, regenerating PolicyReport metrics", clusterName)
+		if err := s.add(obj); err != nil {
+			klog.Warningf("Error regenerating PolicyReport metrics for cluster %s: %v", clusterName, err)
+		}
+	}
+	if err := s.updateCluster(clusterName); err != nil {
+		klog.Warningf("Error regenerating PolicyReport metrics for cluster %s: %v", clusterName, err)
+	}
+}
+
+// updateCluster regenerates the metrics of the cluster namespace from its
+// tracked reports, or removes them if it has none left.
+func (s *policyReportStore) updateCluster(namespace string) error {
+	if s.perCluster == nil {
+		return nil
+	}
+	reports := make([]interface{}, 0, len(s.reports[namespace]))
+	for _, obj := range s.reports[namespace] {
+		reports = append(reports, obj)
+	}
+	cr := newClusterReports(namespace, reports)
+	if len(reports) == 0 {
+		return s.perCluster.Delete(cr)
+	}
+	return s.perCluster.Add(cr)
+}
+
+// reportGroupStore is the view of a policyReportStore given to the reflectors
+// of one API group. The groups hold different reports rather than versions of
+// the same ones, so a relist or a stop of one group leaves the reports of the
+// others alone.
+type reportGroupStore struct {
+	*policyReportStore
+	group string
+}
+
+func (s *reportGroupStore) Replace(list []interface{}, _ string) error {
+	return s.replaceAndResolve(s.group, metav1.NamespaceAll, list)
+}
+
+func (s *reportGroupStore) Drop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.replace(s.group, metav1.NamespaceAll, nil)
+}
+
+func (s *reportGroupStore) ReplaceNamespace(namespace string, list []interface{}, _ string) error {
+	return s.replaceAndResolve(s.group, namespace, list)
+}
+
+func (s *reportGroupStore) DropNamespace(namespace string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.replace(s.group, namespace, nil)
+}
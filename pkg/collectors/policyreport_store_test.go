@@ -0,0 +1,222 @@
+// Copyright (c) 2026 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	ocinfrav1 "github.com/openshift/api/config/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/kube-state-metrics/pkg/metric"
+	metricsstore "k8s.io/kube-state-metrics/pkg/metrics_store"
+	mcv1 "open-cluster-management.io/api/cluster/v1"
+	pr "sigs.k8s.io/wg-policy-prototypes/policy-report/pkg/api/wgpolicyk8s.io/v1alpha2"
+)
+
+// waitForMetrics polls the store until its output contains want, or until it
+// no longer contains it when present is false.
+func waitForMetrics(t *testing.T, store *metricsstore.MetricsStore, want string, present bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		buf := &bytes.Buffer{}
+		store.WriteAll(buf)
+		if strings.Contains(buf.String(), want) == present {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected presence of %q to be %v in:\n%s", want, present, buf.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// newTestPolicyReportStore returns a policyReportStore generating the families
+// of the given options, along with the MetricsStore of its per-cluster
+// families.
+func newTestPolicyReportStore(clusters *clusterCache, opts policyReportOptions) (*policyReportStore, *metricsstore.MetricsStore) {
+	families := getPolicyReportMetricFamilies(clusters, opts)
+	clusterFamilies := getClusterReportMetricFamilies(clusters, opts)
+	prStore := newPolicyReportStore(metricsstore.NewMetricsStore(
+		metric.ExtractMetricFamilyHeaders(families),
+		metric.ComposeMetricGenFuncs(families),
+	), clusters)
+	prStore.perCluster = metricsstore.NewMetricsStore(
+		metric.ExtractMetricFamilyHeaders(clusterFamilies),
+		metric.ComposeMetricGenFuncs(clusterFamilies),
+	)
+	prStore.findings = opts.findings
+	return prStore, prStore.perCluster
+}
+
+func Test_policyReportStore(t *testing.T) {
+	s := scheme.Scheme
+	s.AddKnownTypes(pr.SchemeGroupVersion, &pr.PolicyReport{})
+	s.AddKnownTypes(ocinfrav1.SchemeGroupVersion, &ocinfrav1.ClusterVersion{})
+	s.AddKnownTypes(mcv1.SchemeGroupVersion, &mcv1.ManagedCluster{})
+
+	tests := []struct {
+		name      string
+		idSources []string
+		skipped   bool
+	}{
+		{
+			name:      "no ID yet",
+			idSources: []string{"id.openshift.io"},
+			skipped:   true,
+		}, {
+			name:      "fallback ID",
+			idSources: []string{"id.openshift.io", "name"},
+			skipped:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version := &ocinfrav1.ClusterVersion{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "version",
+				},
+			}
+			mc := &mcv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "importing-cluster",
+				},
+			}
+			prm := &pr.PolicyReport{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "importing-cluster",
+					Namespace: "importing-cluster",
+					UID:       "importing-cluster-uid",
+				},
+				Results: []*pr.PolicyReportResult{
+					{
+						Category: "service_availability",
+						Policy:   "MASTER_DEFINED_AS_MACHINESET",
+						Result:   "fail",
+						Properties: map[string]string{
+							"total_risk": "2",
+						},
+					},
+				},
+			}
+			prU := &unstructured.Unstructured{}
+			if err := scheme.Scheme.Convert(prm, prU, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			client := fake.NewSimpleDynamicClient(s, prU, version, mc)
+			clusters := newSyncedClusterCache(t, client, "", tt.idSources)
+			prStore, store := newTestPolicyReportStore(clusters, policyReportOptions{})
+
+			fallback := `policyreport_info{managed_cluster_id="importing-cluster",category="service_availability",policy="MASTER_DEFINED_AS_MACHINESET",result="fail",severity="moderate",source="",clusterset=""} 1`
+			want := `policyreport_info{managed_cluster_id="imported_id",category="service_availability",policy="MASTER_DEFINED_AS_MACHINESET",result="fail",severity="moderate",source="",clusterset=""} 1`
+
+			if err := prStore.Add(prU); err != nil {
+				t.Fatal(err)
+			}
+			if tt.skipped {
+				waitForMetrics(t, store, "policyreport_info{", false)
+			} else {
+				waitForMetrics(t, store, fallback, true)
+			}
+
+			mc.Status.ClusterClaims = []mcv1.ManagedClusterClaim{
+				{
+					Name:  "id.openshift.io",
+					Value: "imported_id",
+				},
+			}
+			updateManagedCluster(t, client, mc)
+			waitForMetrics(t, store, want, true)
+			waitForMetrics(t, store, fallback, false)
+
+			mc.Labels = map[string]string{
+				"cluster.open-cluster-management.io/clusterset": "team-b",
+			}
+			updateManagedCluster(t, client, mc)
+			waitForMetrics(t, store, strings.Replace(want, `clusterset=""`, `clusterset="team-b"`, 1), true)
+
+			if err := prStore.Delete(prU); err != nil {
+				t.Fatal(err)
+			}
+			waitForMetrics(t, store, "policyreport_info{", false)
+			if len(prStore.reports) != 0 {
+				t.Errorf("expected no tracked reports got %v", prStore.reports)
+			}
+		})
+	}
+}
+
+func Test_reportGroupStore(t *testing.T) {
+	report := func(policy string) *pr.PolicyReport {
+		return &pr.PolicyReport{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      policy,
+				Namespace: "cluster-a",
+				UID:       types.UID(policy),
+			},
+			Results: []*pr.PolicyReportResult{
+				{
+					Category: "security",
+					Policy:   policy,
+					Result:   "fail",
+				},
+			},
+		}
+	}
+	clusters, reports := newPolicyReportFixture(t, policyReportFixture{
+		idSources: []string{"name"},
+		managedClusters: []*mcv1.ManagedCluster{
+			{ObjectMeta: metav1.ObjectMeta{Name: "cluster-a"}},
+		},
+		reports: []*pr.PolicyReport{report("insights-policy"), report("kyverno-policy")},
+	})
+	// The same reports written by another engine to openreports.io.
+	reports[1].SetAPIVersion(openReportsV1alpha1API.String())
+	prStore, store := newTestPolicyReportStore(clusters, policyReportOptions{})
+	for _, report := range reports {
+		if err := prStore.Add(report); err != nil {
+			t.Fatal(err)
+		}
+	}
+	series := func(policy string) string {
+		return fmt.Sprintf(`policyreport_info{managed_cluster_id="cluster-a",category="security",policy=%q,result="fail",severity="unknown",source="",clusterset=""} 1`, policy)
+	}
+	waitForMetrics(t, store, series("insights-policy"), true)
+	waitForMetrics(t, store, series("kyverno-policy"), true)
+
+	// A relist of one group leaves the reports of the other alone.
+	wgPolicy := &reportGroupStore{policyReportStore: prStore, group: wgPolicyV1alpha2API.groupVersion.Group}
+	openReports := &reportGroupStore{policyReportStore: prStore, group: openReportsV1alpha1API.groupVersion.Group}
+	if err := wgPolicy.Replace(nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	waitForMetrics(t, store, series("insights-policy"), false)
+	waitForMetrics(t, store, series("kyverno-policy"), true)
+
+	if err := wgPolicy.ReplaceNamespace("cluster-a", []interface{}{reports[0]}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := openReports.DropNamespace("cluster-a"); err != nil {
+		t.Fatal(err)
+	}
+	waitForMetrics(t, store, series("insights-policy"), true)
+	waitForMetrics(t, store, series("kyverno-policy"), false)
+
+	if err := wgPolicy.Drop(); err != nil {
+		t.Fatal(err)
+	}
+	waitForMetrics(t, store, "policyreport_info{", false)
+	if len(prStore.reports) != 0 {
+		t.Errorf("expected no tracked reports got %v", prStore.reports)
+	}
+}
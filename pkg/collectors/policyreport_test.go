@@ -4,41 +4,85 @@
 package collectors
 
 import (
+	"context"
+	"fmt"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	ocinfrav1 "github.com/openshift/api/config/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/dynamic/fake"
 	"k8s.io/client-go/kubernetes/scheme"
+	clienttesting "k8s.io/client-go/testing"
 	"k8s.io/kube-state-metrics/pkg/metric"
+	metricsstore "k8s.io/kube-state-metrics/pkg/metrics_store"
+	"k8s.io/kube-state-metrics/pkg/whiteblacklist"
 	mcv1 "open-cluster-management.io/api/cluster/v1"
 	pr "sigs.k8s.io/wg-policy-prototypes/policy-report/pkg/api/wgpolicyk8s.io/v1alpha2"
 )
 
-func Test_getPolicyReportMetricFamilies(t *testing.T) {
-	s := scheme.Scheme
+// policyReportFixture describes the objects a PolicyReport metrics test runs
+// against.
+type policyReportFixture struct {
+	// hubID is the ClusterID of the hub's ClusterVersion.
+	hubID            string
+	localClusterName string
+	idSources        []string
+	managedClusters  []*mcv1.ManagedCluster
+	reports          []*pr.PolicyReport
+}
 
+// newPolicyReportFixture returns a cluster cache synced on a fake client holding
+// the ManagedClusters and the ClusterVersion of the fixture, along with its
+// reports converted to unstructured as the reflectors deliver them.
+func newPolicyReportFixture(t *testing.T, f policyReportFixture) (*clusterCache, []*unstructured.Unstructured) {
+	t.Helper()
+	s := scheme.Scheme
 	s.AddKnownTypes(pr.SchemeGroupVersion, &pr.PolicyReport{})
 	s.AddKnownTypes(ocinfrav1.SchemeGroupVersion, &ocinfrav1.ClusterVersion{})
 	s.AddKnownTypes(mcv1.SchemeGroupVersion, &mcv1.ManagedCluster{})
-	version := &ocinfrav1.ClusterVersion{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: "version",
-		},
-		Spec: ocinfrav1.ClusterVersionSpec{
-			ClusterID: "mycluster_id",
+
+	objects := []runtime.Object{
+		&ocinfrav1.ClusterVersion{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "version",
+			},
+			Spec: ocinfrav1.ClusterVersionSpec{
+				ClusterID: ocinfrav1.ClusterID(f.hubID),
+			},
 		},
 	}
+	for _, mc := range f.managedClusters {
+		objects = append(objects, mc)
+	}
+	reports := []*unstructured.Unstructured{}
+	for _, report := range f.reports {
+		reportU := &unstructured.Unstructured{}
+		if err := s.Convert(report, reportU, nil); err != nil {
+			t.Fatal(err)
+		}
+		reports = append(reports, reportU)
+	}
+
+	client := fake.NewSimpleDynamicClient(s, objects...)
+	return newSyncedClusterCache(t, client, f.localClusterName, f.idSources), reports
+}
 
+func Test_getPolicyReportMetricFamilies(t *testing.T) {
 	mc := &mcv1.ManagedCluster{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: "managed-cluster",
+			Labels: map[string]string{
+				"cluster.open-cluster-management.io/clusterset": "team-a",
+			},
 		},
 		Status: mcv1.ManagedClusterStatus{
 			ClusterClaims: []mcv1.ManagedClusterClaim{
@@ -49,6 +93,14 @@ func Test_getPolicyReportMetricFamilies(t *testing.T) {
 			},
 		},
 	}
+	localMC := &mcv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "local-cluster",
+			Labels: map[string]string{
+				"local-cluster": "true",
+			},
+		},
+	}
 	pri := &pr.PolicyReport{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "local-cluster",
@@ -65,12 +117,6 @@ func Test_getPolicyReportMetricFamilies(t *testing.T) {
 			},
 		},
 	}
-	prU := &unstructured.Unstructured{}
-	err := scheme.Scheme.Convert(pri, prU, nil)
-	if err != nil {
-		t.Error(err)
-	}
-
 	prm := &pr.PolicyReport{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "managed-cluster",
@@ -87,12 +133,6 @@ func Test_getPolicyReportMetricFamilies(t *testing.T) {
 			},
 		},
 	}
-	prUM := &unstructured.Unstructured{}
-	err = scheme.Scheme.Convert(prm, prUM, nil)
-	if err != nil {
-		t.Error(err)
-	}
-
 	withDuplicates := &pr.PolicyReport{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "PolicyReport",
@@ -134,38 +174,623 @@ func Test_getPolicyReportMetricFamilies(t *testing.T) {
 			},
 		},
 	}
+	summaryOnly := &pr.PolicyReport{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "summary-only",
+			Namespace: "managed-cluster",
+		},
+		Summary: pr.PolicyReportSummary{
+			Pass: 7,
+			Fail: 2,
+		},
+	}
 
-	prWithDuplicates := &unstructured.Unstructured{}
-	err = scheme.Scheme.Convert(withDuplicates, prWithDuplicates, nil)
-	if err != nil {
-		t.Error(err)
+	clusters, reports := newPolicyReportFixture(t, policyReportFixture{
+		hubID:           "mycluster_id",
+		managedClusters: []*mcv1.ManagedCluster{mc, localMC},
+		reports:         []*pr.PolicyReport{pri, prm, withDuplicates, summaryOnly},
+	})
+	prU, prUM, prWithDuplicates, prSummaryOnly := reports[0], reports[1], reports[2], reports[3]
+	tests := []generateMetricsTestCase{
+		{
+			Obj:         newClusterReports("local-cluster", []interface{}{prU}),
+			MetricNames: []string{"policyreport_info{"},
+			Want:        `policyreport_info{managed_cluster_id="mycluster_id",category="openshift,configuration,service_availability",policy="MASTER_DEFINED_AS_MACHINESET",result="fail",severity="critical",source="",clusterset=""} 1`,
+		}, {
+			Obj:         newClusterReports("managed-cluster", []interface{}{prUM}),
+			MetricNames: []string{"policyreport_info{"},
+			Want:        `policyreport_info{managed_cluster_id="managed-cluster",category="service_availability",policy="MASTER_DEFINED_AS_MACHINESET",result="skip",severity="important",source="",clusterset="team-a"} 1`,
+		}, {
+			Obj:         newClusterReports("local-cluster", []interface{}{prU}),
+			MetricNames: []string{"policyreport_category_info"},
+			Want: strings.Join([]string{
+				`policyreport_category_info{managed_cluster_id="mycluster_id",policy="MASTER_DEFINED_AS_MACHINESET",category="openshift",clusterset=""} 1`,
+				`policyreport_category_info{managed_cluster_id="mycluster_id",policy="MASTER_DEFINED_AS_MACHINESET",category="configuration",clusterset=""} 1`,
+				`policyreport_category_info{managed_cluster_id="mycluster_id",policy="MASTER_DEFINED_AS_MACHINESET",category="service_availability",clusterset=""} 1`,
+			}, "\n"),
+		}, {
+			Obj:         newClusterReports("local-cluster", []interface{}{prWithDuplicates}),
+			MetricNames: []string{"policyreport_category_info"},
+			Want: strings.Join([]string{
+				`policyreport_category_info{managed_cluster_id="mycluster_id",policy="MASTER_DEFINED_AS_MACHINESET",category="service_availability",clusterset=""} 1`,
+				`policyreport_category_info{managed_cluster_id="mycluster_id",policy="MASTER_DEFINED_AS_MACHINESET",category="other",clusterset=""} 1`,
+			}, "\n"),
+		}, {
+			// A policy reported in two reports of the namespace is a single
+			// series per category.
+			Obj:         newClusterReports("local-cluster", []interface{}{prU, prWithDuplicates}),
+			MetricNames: []string{"policyreport_category_info"},
+			Want: strings.Join([]string{
+				`policyreport_category_info{managed_cluster_id="mycluster_id",policy="MASTER_DEFINED_AS_MACHINESET",category="openshift",clusterset=""} 1`,
+				`policyreport_category_info{managed_cluster_id="mycluster_id",policy="MASTER_DEFINED_AS_MACHINESET",category="configuration",clusterset=""} 1`,
+				`policyreport_category_info{managed_cluster_id="mycluster_id",policy="MASTER_DEFINED_AS_MACHINESET",category="service_availability",clusterset=""} 1`,
+				`policyreport_category_info{managed_cluster_id="mycluster_id",policy="MASTER_DEFINED_AS_MACHINESET",category="other",clusterset=""} 1`,
+			}, "\n"),
+		}, {
+			Obj:         newClusterReports("local-cluster", []interface{}{prWithDuplicates}),
+			MetricNames: []string{"policyreport_info{"},
+			Want: strings.Join([]string{
+				`policyreport_info{managed_cluster_id="mycluster_id",category="service_availability",policy="MASTER_DEFINED_AS_MACHINESET",result="fail",severity="important",source="",clusterset=""} 2`,
+				`policyreport_info{managed_cluster_id="mycluster_id",category="other",policy="MASTER_DEFINED_AS_MACHINESET",result="fail",severity="important",source="",clusterset=""} 1`,
+				`policyreport_info{managed_cluster_id="mycluster_id",category="service_availability",policy="MASTER_DEFINED_AS_MACHINESET",result="fail",severity="moderate",source="",clusterset=""} 1`,
+			}, "\n"),
+		}, {
+			Obj:         newClusterReports("local-cluster", []interface{}{prWithDuplicates}),
+			MetricNames: []string{"policyreport_summary"},
+			Want: strings.Join([]string{
+				`policyreport_summary{managed_cluster_id="mycluster_id",result="fail",severity="important",clusterset=""} 3`,
+				`policyreport_summary{managed_cluster_id="mycluster_id",result="fail",severity="moderate",clusterset=""} 1`,
+			}, "\n"),
+		}, {
+			Obj:         newClusterReports("managed-cluster", []interface{}{prSummaryOnly}),
+			MetricNames: []string{"policyreport_summary"},
+			Want: strings.Join([]string{
+				`policyreport_summary{managed_cluster_id="managed-cluster",result="pass",severity="unknown",clusterset="team-a"} 7`,
+				`policyreport_summary{managed_cluster_id="managed-cluster",result="fail",severity="unknown",clusterset="team-a"} 2`,
+			}, "\n"),
+		},
+	}
+	for i, c := range tests {
+		c.Func = metric.ComposeMetricGenFuncs(getPolicyReportMetricFamilies(clusters, policyReportOptions{}))
+		if _, ok := c.Obj.(*clusterReports); ok {
+			c.Func = metric.ComposeMetricGenFuncs(getClusterReportMetricFamilies(clusters, policyReportOptions{}))
+		}
+		if err := c.run(); err != nil {
+			t.Errorf("unexpected collecting result in %v run:\n%s", i, err)
+		}
+	}
+}
+
+func Test_getPolicyReportMetricFamilies_renamedLocalCluster(t *testing.T) {
+	// The hub's ManagedCluster is renamed and only carries the label.
+	hubMC := &mcv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "hub",
+			Labels: map[string]string{
+				"local-cluster": "true",
+			},
+		},
+	}
+	// A spoke that happens to be named local-cluster is not the hub.
+	spokeMC := &mcv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "local-cluster",
+		},
+		Status: mcv1.ManagedClusterStatus{
+			ClusterClaims: []mcv1.ManagedClusterClaim{
+				{
+					Name:  "id.openshift.io",
+					Value: "spoke_id",
+				},
+			},
+		},
+	}
+
+	prs := []*pr.PolicyReport{}
+	for _, ns := range []string{"hub", "local-cluster"} {
+		prs = append(prs, &pr.PolicyReport{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      ns,
+				Namespace: ns,
+			},
+			Results: []*pr.PolicyReportResult{
+				{
+					Category: "service_availability",
+					Policy:   "MASTER_DEFINED_AS_MACHINESET",
+					Result:   "fail",
+					Properties: map[string]string{
+						"total_risk": "4",
+					},
+				},
+			},
+		})
+	}
+	fixture := policyReportFixture{
+		hubID:           "mycluster_id",
+		idSources:       []string{"id.openshift.io"},
+		managedClusters: []*mcv1.ManagedCluster{hubMC, spokeMC},
+		reports:         prs,
+	}
+
+	tests := []struct {
+		name             string
+		localClusterName string
+		// want holds the policyreport_info of the reports of the hub and
+		// local-cluster namespaces.
+		want []string
+	}{
+		{
+			name:             "label",
+			localClusterName: "",
+			want: []string{
+				`policyreport_info{managed_cluster_id="mycluster_id",category="service_availability",policy="MASTER_DEFINED_AS_MACHINESET",result="fail",severity="critical",source="",clusterset=""} 1`,
+				`policyreport_info{managed_cluster_id="spoke_id",category="service_availability",policy="MASTER_DEFINED_AS_MACHINESET",result="fail",severity="critical",source="",clusterset=""} 1`,
+			},
+		}, {
+			name:             "override",
+			localClusterName: "local-cluster",
+			want: []string{
+				"",
+				`policyreport_info{managed_cluster_id="mycluster_id",category="service_availability",policy="MASTER_DEFINED_AS_MACHINESET",result="fail",severity="critical",source="",clusterset=""} 1`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixture.localClusterName = tt.localClusterName
+			clusters, reports := newPolicyReportFixture(t, fixture)
+			for i, report := range reports {
+				c := generateMetricsTestCase{
+					Obj:         newClusterReports(report.GetNamespace(), []interface{}{report}),
+					MetricNames: []string{"policyreport_info{"},
+					Want:        tt.want[i],
+					Func:        metric.ComposeMetricGenFuncs(getClusterReportMetricFamilies(clusters, policyReportOptions{})),
+				}
+				if err := c.run(); err != nil {
+					t.Errorf("unexpected collecting result in %v run:\n%s", i, err)
+				}
+			}
+		})
+	}
+}
+
+func Test_getPolicyReportMetricFamilies_clusterLabels(t *testing.T) {
+	mc := &mcv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "managed-cluster",
+			Labels: map[string]string{
+				"cloud":                             "Amazon",
+				"vendor":                            "EKS",
+				"region.open-cluster-management.io": "us-east-1",
+				"region_open-cluster-management_io": "eu-west-1",
+				"not-allowed":                       "value",
+			},
+		},
+		Status: mcv1.ManagedClusterStatus{
+			ClusterClaims: []mcv1.ManagedClusterClaim{
+				{
+					Name:  "id.openshift.io",
+					Value: "managed-cluster",
+				},
+			},
+		},
+	}
+	prm := &pr.PolicyReport{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "managed-cluster",
+			Namespace: "managed-cluster",
+		},
+		Results: []*pr.PolicyReportResult{
+			{
+				Category: "service_availability",
+				Policy:   "MASTER_DEFINED_AS_MACHINESET",
+				Result:   "fail",
+				Properties: map[string]string{
+					"total_risk": "3",
+				},
+			},
+		},
+	}
+	clusters, reports := newPolicyReportFixture(t, policyReportFixture{
+		managedClusters: []*mcv1.ManagedCluster{mc},
+		reports:         []*pr.PolicyReport{prm},
+	})
+	opts := policyReportOptions{
+		clusterLabels: []string{
+			"cloud",
+			"environment",
+			"region.open-cluster-management.io",
+			"region_open-cluster-management_io",
+			"vendor",
+		},
+	}
+	c := generateMetricsTestCase{
+		Obj:         newClusterReports("managed-cluster", []interface{}{reports[0]}),
+		MetricNames: []string{"policyreport_info{"},
+		Want:        `policyreport_info{managed_cluster_id="managed-cluster",category="service_availability",policy="MASTER_DEFINED_AS_MACHINESET",result="fail",severity="important",source="",label_cloud="Amazon",label_environment="",label_region_open_cluster_management_io="us-east-1",label_vendor="EKS",clusterset=""} 1`,
+		Func:        metric.ComposeMetricGenFuncs(getClusterReportMetricFamilies(clusters, opts)),
+	}
+	if err := c.run(); err != nil {
+		t.Errorf("unexpected collecting result:\n%s", err)
+	}
+}
+
+func Test_getPolicyReportMetricFamilies_freshness(t *testing.T) {
+	mc := &mcv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "managed-cluster",
+		},
+		Status: mcv1.ManagedClusterStatus{
+			ClusterClaims: []mcv1.ManagedClusterClaim{
+				{
+					Name:  "id.openshift.io",
+					Value: "managed-cluster",
+				},
+			},
+		},
+	}
+
+	created := time.Unix(1700000000, 0)
+	updated := metav1.NewTime(created.Add(2 * time.Hour))
+	fresh := &pr.PolicyReport{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "fresh",
+			Namespace:         "managed-cluster",
+			CreationTimestamp: metav1.NewTime(created),
+			ManagedFields: []metav1.ManagedFieldsEntry{
+				{
+					Manager: "insights-client",
+					Time:    &updated,
+				},
+			},
+		},
+		Results: []*pr.PolicyReportResult{
+			{
+				Policy:    "MASTER_DEFINED_AS_MACHINESET",
+				Timestamp: metav1.Timestamp{Seconds: created.Add(3 * time.Hour).Unix()},
+			}, {
+				Policy:    "NODES_MINIMUM_REQUIREMENTS_NOT_MET",
+				Timestamp: metav1.Timestamp{Seconds: created.Add(time.Hour).Unix()},
+			},
+		},
+	}
+	stale := &pr.PolicyReport{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "stale",
+			Namespace:         "managed-cluster",
+			CreationTimestamp: metav1.NewTime(created),
+		},
 	}
 
-	client := fake.NewSimpleDynamicClient(s, prU, prUM, version, mc)
+	defer func(n func() time.Time) { now = n }(now)
+	now = func() time.Time { return created.Add(4 * time.Hour) }
+
+	clusters, reports := newPolicyReportFixture(t, policyReportFixture{
+		managedClusters: []*mcv1.ManagedCluster{mc},
+		reports:         []*pr.PolicyReport{fresh, stale},
+	})
+	prFresh, prStale := reports[0], reports[1]
 	tests := []generateMetricsTestCase{
 		{
-			Obj:  prU,
-			Want: `policyreport_info{managed_cluster_id="mycluster_id",category="openshift,configuration,service_availability",policy="MASTER_DEFINED_AS_MACHINESET",result="fail",severity="critical"} 1`,
+			Obj:         newClusterReports("managed-cluster", []interface{}{prFresh}),
+			MetricNames: []string{"policyreport_last_updated_timestamp_seconds", "policyreport_stale"},
+			Want: strings.Join([]string{
+				`policyreport_last_updated_timestamp_seconds{managed_cluster_id="managed-cluster",clusterset=""} 1.7000108e+09`,
+				`policyreport_stale{managed_cluster_id="managed-cluster",clusterset=""} 0`,
+			}, "\n"),
 		}, {
-			Obj:  prUM,
-			Want: `policyreport_info{managed_cluster_id="managed-cluster",category="service_availability",policy="MASTER_DEFINED_AS_MACHINESET",result="skip",severity="important"} 1`,
+			Obj:         newClusterReports("managed-cluster", []interface{}{prStale}),
+			MetricNames: []string{"policyreport_last_updated_timestamp_seconds", "policyreport_stale"},
+			Want: strings.Join([]string{
+				`policyreport_last_updated_timestamp_seconds{managed_cluster_id="managed-cluster",clusterset=""} 1.7e+09`,
+				`policyreport_stale{managed_cluster_id="managed-cluster",clusterset=""} 1`,
+			}, "\n"),
 		}, {
-			Obj: prWithDuplicates,
+			// A stale report next to a fresh one leaves the cluster fresh.
+			Obj:         newClusterReports("managed-cluster", []interface{}{prStale, prFresh}),
+			MetricNames: []string{"policyreport_last_updated_timestamp_seconds", "policyreport_stale"},
 			Want: strings.Join([]string{
-				`policyreport_info{managed_cluster_id="mycluster_id",category="service_availability",policy="MASTER_DEFINED_AS_MACHINESET",result="fail",severity="important"} 2`,
-				`policyreport_info{managed_cluster_id="mycluster_id",category="other",policy="MASTER_DEFINED_AS_MACHINESET",result="fail",severity="important"} 1`,
-				`policyreport_info{managed_cluster_id="mycluster_id",category="service_availability",policy="MASTER_DEFINED_AS_MACHINESET",result="fail",severity="moderate"} 1`,
+				`policyreport_last_updated_timestamp_seconds{managed_cluster_id="managed-cluster",clusterset=""} 1.7000108e+09`,
+				`policyreport_stale{managed_cluster_id="managed-cluster",clusterset=""} 0`,
 			}, "\n"),
 		},
 	}
 	for i, c := range tests {
-		c.Func = metric.ComposeMetricGenFuncs(getPolicyReportMetricFamilies(client))
+		c.Func = metric.ComposeMetricGenFuncs(getClusterReportMetricFamilies(clusters, policyReportOptions{
+			maxReportAge: 2 * time.Hour,
+		}))
 		if err := c.run(); err != nil {
 			t.Errorf("unexpected collecting result in %v run:\n%s", i, err)
 		}
 	}
 }
 
+func Test_getPolicyReportMetricFamilies_sources(t *testing.T) {
+	report := &pr.PolicyReport{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "mixed",
+			Namespace: "managed-cluster",
+		},
+		Results: []*pr.PolicyReportResult{
+			{
+				Source:   "insights",
+				Category: "service_availability",
+				Policy:   "MASTER_DEFINED_AS_MACHINESET",
+				Result:   "fail",
+				Properties: map[string]string{
+					"total_risk": "3",
+				},
+			}, {
+				Source:   "kyverno",
+				Category: "Pod Security Standards",
+				Policy:   "disallow-privileged-containers",
+				Result:   "fail",
+			},
+		},
+	}
+	// A report carrying only a Summary has no source.
+	summaryOnly := &pr.PolicyReport{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "summary-only",
+			Namespace: "managed-cluster",
+		},
+		Summary: pr.PolicyReportSummary{
+			Fail: 2,
+		},
+	}
+
+	clusters, reports := newPolicyReportFixture(t, policyReportFixture{
+		idSources: []string{"name"},
+		managedClusters: []*mcv1.ManagedCluster{
+			{ObjectMeta: metav1.ObjectMeta{Name: "managed-cluster"}},
+		},
+		reports: []*pr.PolicyReport{report, summaryOnly},
+	})
+	prU, summaryOnlyU := reports[0], reports[1]
+
+	insights := `policyreport_info{managed_cluster_id="managed-cluster",category="service_availability",policy="MASTER_DEFINED_AS_MACHINESET",result="fail",severity="important",source="insights",clusterset=""} 1`
+	kyverno := `policyreport_info{managed_cluster_id="managed-cluster",category="Pod Security Standards",policy="disallow-privileged-containers",result="fail",severity="unknown",source="kyverno",clusterset=""} 1`
+	tests := []struct {
+		name    string
+		sources []string
+		want    []string
+		summary []string
+	}{
+		{
+			name: "every source",
+			want: []string{insights, kyverno},
+			summary: []string{
+				`policyreport_summary{managed_cluster_id="managed-cluster",result="fail",severity="important",clusterset=""} 1`,
+				`policyreport_summary{managed_cluster_id="managed-cluster",result="fail",severity="unknown",clusterset=""} 3`,
+			},
+		}, {
+			name:    "insights only",
+			sources: []string{"insights"},
+			want:    []string{insights},
+			summary: []string{
+				`policyreport_summary{managed_cluster_id="managed-cluster",result="fail",severity="important",clusterset=""} 1`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := policyReportOptions{results: newResultOptions(tt.sources, nil)}
+			c := generateMetricsTestCase{
+				Obj:         newClusterReports("managed-cluster", []interface{}{prU}),
+				MetricNames: []string{"policyreport_info{"},
+				Want:        strings.Join(tt.want, "\n"),
+				Func:        metric.ComposeMetricGenFuncs(getClusterReportMetricFamilies(clusters, opts)),
+			}
+			if err := c.run(); err != nil {
+				t.Errorf("unexpected collecting result:\n%s", err)
+			}
+			c = generateMetricsTestCase{
+				Obj:         newClusterReports("managed-cluster", []interface{}{prU, summaryOnlyU}),
+				MetricNames: []string{"policyreport_summary"},
+				Want:        strings.Join(tt.summary, "\n"),
+				Func:        metric.ComposeMetricGenFuncs(getClusterReportMetricFamilies(clusters, opts)),
+			}
+			if err := c.run(); err != nil {
+				t.Errorf("unexpected collecting result:\n%s", err)
+			}
+		})
+	}
+}
+
+func Test_getPolicyReportMetricFamilies_risk(t *testing.T) {
+	report := &pr.PolicyReport{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "risky",
+			Namespace: "managed-cluster",
+		},
+		Results: []*pr.PolicyReportResult{
+			{
+				Source: "insights",
+				Policy: "MASTER_DEFINED_AS_MACHINESET",
+				Properties: map[string]string{
+					"total_risk": "2",
+					"likelihood": "1",
+					"impact":     "3",
+				},
+			}, {
+				// The riskiest result of a policy rates it.
+				Source: "insights",
+				Policy: "MASTER_DEFINED_AS_MACHINESET",
+				Properties: map[string]string{
+					"total_risk": "3",
+					"likelihood": "not a number",
+				},
+			}, {
+				Source: "kyverno",
+				Policy: "disallow-privileged-containers",
+				Properties: map[string]string{
+					"total_risk": "4",
+				},
+			},
+		},
+	}
+
+	clusters, reports := newPolicyReportFixture(t, policyReportFixture{
+		idSources: []string{"name"},
+		managedClusters: []*mcv1.ManagedCluster{
+			{ObjectMeta: metav1.ObjectMeta{Name: "managed-cluster"}},
+		},
+		reports: []*pr.PolicyReport{report},
+	})
+	c := generateMetricsTestCase{
+		Obj:         reports[0],
+		MetricNames: []string{"policyreport_result_risk"},
+		Want: strings.Join([]string{
+			`policyreport_result_risk{managed_cluster_id="managed-cluster",policy="MASTER_DEFINED_AS_MACHINESET",dimension="total_risk",clusterset=""} 3`,
+			`policyreport_result_risk{managed_cluster_id="managed-cluster",policy="MASTER_DEFINED_AS_MACHINESET",dimension="likelihood",clusterset=""} 1`,
+			`policyreport_result_risk{managed_cluster_id="managed-cluster",policy="MASTER_DEFINED_AS_MACHINESET",dimension="impact",clusterset=""} 3`,
+		}, "\n"),
+		Func: metric.ComposeMetricGenFuncs(getPolicyReportMetricFamilies(clusters, policyReportOptions{
+			results: newResultOptions([]string{"insights"}, nil),
+		})),
+	}
+	if err := c.run(); err != nil {
+		t.Errorf("unexpected collecting result:\n%s", err)
+	}
+}
+
+func Test_getPolicyReportMetricFamilies_resources(t *testing.T) {
+	report := &pr.PolicyReport{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "affected",
+			Namespace: "managed-cluster",
+		},
+		Results: []*pr.PolicyReportResult{
+			{
+				Policy: "NODES_MINIMUM_REQUIREMENTS_NOT_MET",
+				Subjects: []*corev1.ObjectReference{
+					{Kind: "Node", Name: "worker-1"},
+					{Kind: "Node", Name: "worker-0"},
+				},
+			}, {
+				// The same resource affected by another rule of the policy
+				// is a single series.
+				Policy: "NODES_MINIMUM_REQUIREMENTS_NOT_MET",
+				Rule:   "NODES_MINIMUM_REQUIREMENTS_NOT_MET|MEMORY",
+				Subjects: []*corev1.ObjectReference{
+					{Kind: "Node", Name: "worker-0"},
+				},
+			}, {
+				Policy: "MASTER_DEFINED_AS_MACHINESET",
+				Subjects: []*corev1.ObjectReference{
+					{Kind: "MachineSet", Namespace: "openshift-machine-api", Name: "master"},
+				},
+			},
+		},
+	}
+	other := &pr.PolicyReport{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "other",
+			Namespace: "managed-cluster",
+		},
+		Results: []*pr.PolicyReportResult{
+			{
+				// A resource affected in both reports is a single series.
+				Policy: "MASTER_DEFINED_AS_MACHINESET",
+				Subjects: []*corev1.ObjectReference{
+					{Kind: "MachineSet", Namespace: "openshift-machine-api", Name: "master"},
+				},
+			}, {
+				Policy: "NODES_MINIMUM_REQUIREMENTS_NOT_MET",
+				Subjects: []*corev1.ObjectReference{
+					{Kind: "Node", Name: "worker-2"},
+				},
+			},
+		},
+	}
+
+	clusters, reports := newPolicyReportFixture(t, policyReportFixture{
+		idSources: []string{"name"},
+		managedClusters: []*mcv1.ManagedCluster{
+			{ObjectMeta: metav1.ObjectMeta{Name: "managed-cluster"}},
+		},
+		reports: []*pr.PolicyReport{report, other},
+	})
+	prU, otherU := reports[0], reports[1]
+
+	master := `policyreport_result_resources{managed_cluster_id="managed-cluster",policy="MASTER_DEFINED_AS_MACHINESET",kind="MachineSet",namespace="openshift-machine-api",name="master",clusterset=""} 1`
+	worker0 := `policyreport_result_resources{managed_cluster_id="managed-cluster",policy="NODES_MINIMUM_REQUIREMENTS_NOT_MET",kind="Node",namespace="",name="worker-0",clusterset=""} 1`
+	worker1 := `policyreport_result_resources{managed_cluster_id="managed-cluster",policy="NODES_MINIMUM_REQUIREMENTS_NOT_MET",kind="Node",namespace="",name="worker-1",clusterset=""} 1`
+	tests := []struct {
+		name    string
+		limit   int
+		reports []interface{}
+		want    []string
+	}{
+		{
+			name:  "under the limit",
+			limit: 10,
+			want: []string{master, worker0, worker1,
+				`policyreport_result_resources_dropped{managed_cluster_id="managed-cluster",clusterset=""} 0`},
+		}, {
+			name:  "over the limit",
+			limit: 2,
+			want: []string{master, worker0,
+				`policyreport_result_resources_dropped{managed_cluster_id="managed-cluster",clusterset=""} 1`},
+		}, {
+			// The limit caps the resources of the cluster, not of each
+			// report.
+			name:    "over the limit across reports",
+			limit:   3,
+			reports: []interface{}{prU, otherU},
+			want: []string{master, worker0, worker1,
+				`policyreport_result_resources_dropped{managed_cluster_id="managed-cluster",clusterset=""} 1`},
+		}, {
+			name: "disabled",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.reports == nil {
+				tt.reports = []interface{}{prU}
+			}
+			c := generateMetricsTestCase{
+				Obj:         newClusterReports("managed-cluster", tt.reports),
+				MetricNames: []string{"policyreport_result_resources"},
+				Want:        strings.Join(tt.want, "\n"),
+				Func: metric.ComposeMetricGenFuncs(getClusterReportMetricFamilies(clusters, policyReportOptions{
+					maxResultResources: tt.limit,
+				})),
+			}
+			if err := c.run(); err != nil {
+				t.Errorf("unexpected collecting result:\n%s", err)
+			}
+		})
+	}
+}
+
+func Test_getPolicyReportMetricFamilies_whiteBlackList(t *testing.T) {
+	l, err := whiteblacklist.New(map[string]struct{}{}, map[string]struct{}{"policyreport_summary": {}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	families := append(getPolicyReportMetricFamilies(nil, policyReportOptions{}), getClusterReportMetricFamilies(nil, policyReportOptions{})...)
+	for _, f := range metric.FilterMetricFamilies(l, families) {
+		names = append(names, f.Name)
+	}
+	for _, name := range names {
+		if name == "policyreport_summary" {
+			t.Errorf("expected policyreport_summary to be filtered out of %v", names)
+		}
+	}
+	kept := false
+	for _, name := range names {
+		kept = kept || name == "policyreport_info"
+	}
+	if !kept {
+		t.Errorf("expected policyreport_info to be kept in %v", names)
+	}
+}
+
 func Test_createPolicyReportListWatchWithClient(t *testing.T) {
 	s := runtime.NewScheme()
 	s.AddKnownTypes(corev1.SchemeGroupVersion, &corev1.Namespace{})
@@ -224,7 +849,7 @@ func Test_createPolicyReportListWatchWithClient(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := createPolicyReportListWatchWithClient(tt.args.client, tt.args.ns)
+			got := createPolicyReportListWatchWithClient(tt.args.client, wgPolicyV1alpha2API, reportSelectors{}, tt.args.ns)
 			l, err := got.ListFunc(metav1.ListOptions{})
 			if (err != nil) != tt.wantErr {
 				t.Error(err)
@@ -247,3 +872,143 @@ func Test_createPolicyReportListWatchWithClient(t *testing.T) {
 		})
 	}
 }
+
+func Test_reportSelectors(t *testing.T) {
+	s := runtime.NewScheme()
+	s.AddKnownTypes(pr.SchemeGroupVersion, &pr.PolicyReport{}, &pr.PolicyReportList{})
+
+	report := func(name string, labels map[string]string) *pr.PolicyReport {
+		return &pr.PolicyReport{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "local-cluster",
+				Labels:    labels,
+			},
+		}
+	}
+	client := fake.NewSimpleDynamicClient(s,
+		report("insights", map[string]string{"app.kubernetes.io/managed-by": "insights"}),
+		report("kyverno", map[string]string{"app.kubernetes.io/managed-by": "kyverno"}))
+
+	selectors, err := newReportSelectors("app.kubernetes.io/managed-by=insights", "metadata.namespace=local-cluster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lw := createPolicyReportListWatchWithClient(client, wgPolicyV1alpha2API, selectors, "local-cluster")
+	l, err := lw.ListFunc(metav1.ListOptions{ResourceVersion: "0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if items := l.(*unstructured.UnstructuredList).Items; len(items) != 1 || items[0].GetName() != "insights" {
+		t.Errorf("expected the insights report only got %v", items)
+	}
+	w, err := lw.WatchFunc(metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Stop()
+
+	actions := client.Actions()
+	if len(actions) != 2 {
+		t.Fatalf("expected a list and a watch got %v", actions)
+	}
+	for _, action := range actions {
+		var labels, fields string
+		switch a := action.(type) {
+		case clienttesting.ListAction:
+			labels, fields = a.GetListRestrictions().Labels.String(), a.GetListRestrictions().Fields.String()
+		case clienttesting.WatchAction:
+			labels, fields = a.GetWatchRestrictions().Labels.String(), a.GetWatchRestrictions().Fields.String()
+		}
+		if labels != selectors.label || fields != selectors.field {
+			t.Errorf("%s: expected selectors %q and %q got %q and %q", action.GetVerb(),
+				selectors.label, selectors.field, labels, fields)
+		}
+	}
+
+	for _, invalid := range [][2]string{{"app in (", ""}, {"", "metadata.name"}} {
+		if _, err := newReportSelectors(invalid[0], invalid[1]); err == nil {
+			t.Errorf("expected selectors %q and %q to fail", invalid[0], invalid[1])
+		}
+	}
+}
+
+// BenchmarkPolicyReportRelist measures a relist of 3,000 PolicyReports, one per
+// managed cluster, from the list call to the metrics of every report, and
+// reports the number of API calls it takes.
+func BenchmarkPolicyReportRelist(b *testing.B) {
+	const reports = 3000
+
+	s := runtime.NewScheme()
+	s.AddKnownTypes(pr.SchemeGroupVersion, &pr.PolicyReport{}, &pr.PolicyReportList{})
+	s.AddKnownTypes(ocinfrav1.SchemeGroupVersion, &ocinfrav1.ClusterVersion{}, &ocinfrav1.ClusterVersionList{})
+	s.AddKnownTypes(mcv1.SchemeGroupVersion, &mcv1.ManagedCluster{}, &mcv1.ManagedClusterList{})
+
+	objects := []runtime.Object{}
+	for i := 0; i < reports; i++ {
+		name := fmt.Sprintf("cluster-%d", i)
+		objects = append(objects,
+			&mcv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+			},
+			&pr.PolicyReport{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: name,
+					UID:       types.UID(name),
+				},
+				Results: []*pr.PolicyReportResult{
+					{
+						Category: "service_availability",
+						Policy:   "MASTER_DEFINED_AS_MACHINESET",
+						Result:   "fail",
+						Properties: map[string]string{
+							"total_risk": "3",
+						},
+					},
+				},
+			})
+	}
+	client := fake.NewSimpleDynamicClient(s, objects...)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	clusters := newClusterCache(client, "", []string{"name"})
+	if !clusters.start(ctx) {
+		b.Fatal("cluster cache did not sync")
+	}
+	families := getPolicyReportMetricFamilies(clusters, policyReportOptions{})
+	store := newPolicyReportStore(metricsstore.NewMetricsStore(
+		metric.ExtractMetricFamilyHeaders(families),
+		metric.ComposeMetricGenFuncs(families),
+	), clusters)
+	lw := createPolicyReportListWatchWithClient(client, wgPolicyV1alpha2API, reportSelectors{}, metav1.NamespaceAll)
+
+	client.ClearActions()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l, err := lw.ListFunc(metav1.ListOptions{})
+		if err != nil {
+			b.Fatal(err)
+		}
+		items, err := meta.ExtractList(l)
+		if err != nil {
+			b.Fatal(err)
+		}
+		list := make([]interface{}, 0, len(items))
+		for _, item := range items {
+			list = append(list, item)
+		}
+		if err := store.Replace(list, ""); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+	calls := 0
+	for _, action := range client.Actions() {
+		if action.GetResource() == wgPolicyV1alpha2API.gvr() {
+			calls++
+		}
+	}
+	b.ReportMetric(float64(calls)/float64(b.N), "apicalls/relist")
+}
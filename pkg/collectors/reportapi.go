@@ -0,0 +1,233 @@
+// Copyright (c) 2026 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/wg-policy-prototypes/policy-report/pkg/api/wgpolicyk8s.io/v1alpha2"
+)
+
+// reportAPI is a version of the PolicyReport API: the resources holding the
+// namespaced and cluster-scoped reports, and how to normalize them.
+type reportAPI struct {
+	groupVersion    schema.GroupVersion
+	resource        string
+	clusterResource string
+	decode          func(content map[string]interface{}) (*policyReport, error)
+}
+
+var (
+	wgPolicyV1alpha2API = reportAPI{
+		groupVersion:    schema.GroupVersion{Group: "wgpolicyk8s.io", Version: "v1alpha2"},
+		resource:        "policyreports",
+		clusterResource: "clusterpolicyreports",
+		decode:          decodeV1alpha2Report,
+	}
+	wgPolicyV1beta1API = reportAPI{
+		groupVersion:    schema.GroupVersion{Group: "wgpolicyk8s.io", Version: "v1beta1"},
+		resource:        "policyreports",
+		clusterResource: "clusterpolicyreports",
+		decode:          decodeReport,
+	}
+	openReportsV1alpha1API = reportAPI{
+		groupVersion:    schema.GroupVersion{Group: "openreports.io", Version: "v1alpha1"},
+		resource:        "reports",
+		clusterResource: "clusterreports",
+		decode:          decodeReport,
+	}
+
+	// reportAPIs are the supported API versions, most preferred first within
+	// each group. The groups hold different reports, written by different
+	// engines, rather than versions of the same ones, so they are not ranked
+	// against each other.
+	reportAPIs = []reportAPI{wgPolicyV1beta1API, wgPolicyV1alpha2API, openReportsV1alpha1API}
+
+	// defaultReportAPI is used to read objects of an unknown version.
+	defaultReportAPI = wgPolicyV1alpha2API
+)
+
+func (a reportAPI) String() string {
+	return a.groupVersion.String()
+}
+
+func (a reportAPI) gvr() schema.GroupVersionResource {
+	return a.groupVersion.WithResource(a.resource)
+}
+
+func (a reportAPI) clusterGVR() schema.GroupVersionResource {
+	return a.groupVersion.WithResource(a.clusterResource)
+}
+
+// reportAPIByVersion returns the supported API of the given group/version.
+func reportAPIByVersion(groupVersion string) (reportAPI, error) {
+	for _, api := range reportAPIs {
+		if api.String() == groupVersion {
+			return api, nil
+		}
+	}
+	return reportAPI{}, fmt.Errorf("unsupported PolicyReport API version %q, expected one of %v", groupVersion, reportAPIs)
+}
+
+// reportAPIGroups splits the given API versions by group, keeping their order.
+func reportAPIGroups(apis []reportAPI) [][]reportAPI {
+	groups := [][]reportAPI{}
+	index := map[string]int{}
+	for _, api := range apis {
+		i, ok := index[api.groupVersion.Group]
+		if !ok {
+			i = len(groups)
+			index[api.groupVersion.Group] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], api)
+	}
+	return groups
+}
+
+// policyReport is the version-independent view of a PolicyReport, or of a
+// cluster-scoped report, that the metrics are generated from.
+type policyReport struct {
+	metav1.ObjectMeta
+	// summary maps a result, such as pass or fail, to its count.
+	summary map[string]int
+	results []reportResult
+}
+
+type reportResult struct {
+	source     string
+	policy     string
+	rule       string
+	category   string
+	severity   string
+	result     string
+	timestamp  time.Time
+	properties map[string]string
+	resources  []corev1.ObjectReference
+}
+
+// reportAPIOf returns the API of the given report, defaultReportAPI if its
+// version is not supported.
+func reportAPIOf(obj *unstructured.Unstructured) reportAPI {
+	return reportAPIOfGroupVersion(obj.GroupVersionKind().GroupVersion())
+}
+
+// reportAPIOfGroupVersion returns the API of the given group/version,
+// defaultReportAPI if it is not supported.
+func reportAPIOfGroupVersion(groupVersion schema.GroupVersion) reportAPI {
+	for _, api := range reportAPIs {
+		if api.groupVersion == groupVersion {
+			return api
+		}
+	}
+	return defaultReportAPI
+}
+
+// decodePolicyReport normalizes a report of any supported version.
+func decodePolicyReport(obj *unstructured.Unstructured) (*policyReport, error) {
+	return reportAPIOf(obj).decode(obj.UnstructuredContent())
+}
+
+// decodeV1alpha2Report reads a wgpolicyk8s.io/v1alpha2 PolicyReport or
+// ClusterPolicyReport, which share the same layout.
+func decodeV1alpha2Report(content map[string]interface{}) (*policyReport, error) {
+	pr := &v1alpha2.PolicyReport{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(content, pr); err != nil {
+		return nil, err
+	}
+	report := &policyReport{
+		ObjectMeta: pr.ObjectMeta,
+		summary:    summaryCounts(pr.Summary.Pass, pr.Summary.Fail, pr.Summary.Warn, pr.Summary.Error, pr.Summary.Skip),
+	}
+	for _, r := range pr.Results {
+		if r == nil {
+			continue
+		}
+		resources := []corev1.ObjectReference{}
+		for _, subject := range r.Subjects {
+			if subject != nil {
+				resources = append(resources, *subject)
+			}
+		}
+		report.results = append(report.results, reportResult{
+			source:     r.Source,
+			policy:     r.Policy,
+			rule:       r.Rule,
+			category:   r.Category,
+			severity:   string(r.Severity),
+			result:     string(r.Result),
+			timestamp:  timestampTime(r.Timestamp),
+			properties: r.Properties,
+			resources:  resources,
+		})
+	}
+	return report, nil
+}
+
+// wireReport is the layout shared by the wgpolicyk8s.io/v1beta1 and
+// openreports.io/v1alpha1 reports, whose types are not vendored.
+type wireReport struct {
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Summary           struct {
+		Pass  int `json:"pass,omitempty"`
+		Fail  int `json:"fail,omitempty"`
+		Warn  int `json:"warn,omitempty"`
+		Error int `json:"error,omitempty"`
+		Skip  int `json:"skip,omitempty"`
+	} `json:"summary,omitempty"`
+	Results []struct {
+		Source     string                   `json:"source,omitempty"`
+		Policy     string                   `json:"policy"`
+		Rule       string                   `json:"rule,omitempty"`
+		Category   string                   `json:"category,omitempty"`
+		Severity   string                   `json:"severity,omitempty"`
+		Result     string                   `json:"result,omitempty"`
+		Timestamp  metav1.Timestamp         `json:"timestamp,omitempty"`
+		Properties map[string]string        `json:"properties,omitempty"`
+		Resources  []corev1.ObjectReference `json:"resources,omitempty"`
+	} `json:"results,omitempty"`
+}
+
+func decodeReport(content map[string]interface{}) (*policyReport, error) {
+	wr := &wireReport{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(content, wr); err != nil {
+		return nil, err
+	}
+	report := &policyReport{
+		ObjectMeta: wr.ObjectMeta,
+		summary:    summaryCounts(wr.Summary.Pass, wr.Summary.Fail, wr.Summary.Warn, wr.Summary.Error, wr.Summary.Skip),
+	}
+	for _, r := range wr.Results {
+		report.results = append(report.results, reportResult{
+			source:     r.Source,
+			policy:     r.Policy,
+			rule:       r.Rule,
+			category:   r.Category,
+			severity:   r.Severity,
+			result:     r.Result,
+			timestamp:  timestampTime(r.Timestamp),
+			properties: r.Properties,
+			resources:  r.Resources,
+		})
+	}
+	return report, nil
+}
+
+func summaryCounts(pass, fail, warn, errored, skip int) map[string]int {
+	return map[string]int{"pass": pass, "fail": fail, "warn": warn, "error": errored, "skip": skip}
+}
+
+// timestampTime returns the time of the timestamp, or the zero time if unset.
+func timestampTime(ts metav1.Timestamp) time.Time {
+	if ts.Seconds == 0 {
+		return time.Time{}
+	}
+	return time.Unix(ts.Seconds, int64(ts.Nanos))
+}
@@ -0,0 +1,141 @@
+// Copyright (c) 2026 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func Test_reportAPIByVersion(t *testing.T) {
+	for _, api := range reportAPIs {
+		got, err := reportAPIByVersion(api.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.gvr() != api.gvr() || got.clusterGVR() != api.clusterGVR() {
+			t.Errorf("expected %v got %v", api.gvr(), got.gvr())
+		}
+	}
+	if _, err := reportAPIByVersion("wgpolicyk8s.io/v2"); err == nil {
+		t.Error("expected an unsupported version to fail")
+	}
+}
+
+func Test_reportAPIGroups(t *testing.T) {
+	got := reportAPIGroups(reportAPIs)
+	want := [][]reportAPI{{wgPolicyV1beta1API, wgPolicyV1alpha2API}, {openReportsV1alpha1API}}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d groups got %v", len(want), got)
+	}
+	for i := range want {
+		if len(got[i]) != len(want[i]) {
+			t.Fatalf("expected %v got %v", want[i], got[i])
+		}
+		for j := range want[i] {
+			if got[i][j].String() != want[i][j].String() {
+				t.Errorf("expected %v got %v", want[i], got[i])
+			}
+		}
+	}
+}
+
+func Test_decodePolicyReport(t *testing.T) {
+	report := func(apiVersion string, kind string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": apiVersion,
+			"kind":       kind,
+			"metadata": map[string]interface{}{
+				"name":      "managed-cluster",
+				"namespace": "managed-cluster",
+			},
+			"summary": map[string]interface{}{
+				"fail": int64(1),
+				"pass": int64(3),
+			},
+			"results": []interface{}{
+				map[string]interface{}{
+					"source":   "insights",
+					"category": "service_availability",
+					"policy":   "MASTER_DEFINED_AS_MACHINESET",
+					"rule":     "MASTER_DEFINED_AS_MACHINESET|RULE",
+					"result":   "fail",
+					"timestamp": map[string]interface{}{
+						"seconds": int64(1700000000),
+						"nanos":   int64(0),
+					},
+					"properties": map[string]interface{}{
+						"total_risk": "3",
+					},
+					"resources": []interface{}{
+						map[string]interface{}{
+							"kind": "Node",
+							"name": "master-0",
+						},
+					},
+				},
+			},
+		}}
+	}
+
+	wantResults := map[metricResult]int{
+		{
+			clusterID: "managed_cluster_id",
+			category:  "service_availability",
+			policy:    "MASTER_DEFINED_AS_MACHINESET",
+			result:    "fail",
+			severity:  "important",
+			source:    "insights",
+		}: 1,
+	}
+
+	tests := []struct {
+		name string
+		obj  *unstructured.Unstructured
+	}{
+		{
+			name: "wgpolicyk8s.io/v1alpha2",
+			obj:  report("wgpolicyk8s.io/v1alpha2", "PolicyReport"),
+		}, {
+			name: "wgpolicyk8s.io/v1beta1",
+			obj:  report("wgpolicyk8s.io/v1beta1", "PolicyReport"),
+		}, {
+			name: "openreports.io/v1alpha1",
+			obj:  report("openreports.io/v1alpha1", "Report"),
+		}, {
+			name: "openreports.io/v1alpha1 cluster-scoped",
+			obj:  report("openreports.io/v1alpha1", "ClusterReport"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodePolicyReport(tt.obj)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.GetName() != "managed-cluster" {
+				t.Errorf("expected name managed-cluster got %s", got.GetName())
+			}
+			if got.summary["fail"] != 1 || got.summary["pass"] != 3 {
+				t.Errorf("unexpected summary %v", got.summary)
+			}
+			if len(got.results) != 1 {
+				t.Fatalf("expected 1 result got %d", len(got.results))
+			}
+			r := got.results[0]
+			if r.source != "insights" || r.rule != "MASTER_DEFINED_AS_MACHINESET|RULE" || len(r.resources) != 1 || r.resources[0].Name != "master-0" {
+				t.Errorf("unexpected result %+v", r)
+			}
+			if results := getResults("managed_cluster_id", got, resultOptions{}); !reflect.DeepEqual(results, wantResults) {
+				t.Errorf("expected %v got %v", wantResults, results)
+			}
+			if updated := lastUpdated(got); !updated.Equal(time.Unix(1700000000, 0)) {
+				t.Errorf("expected last update at %v got %v", time.Unix(1700000000, 0), updated)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2026 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+	"k8s.io/kube-state-metrics/pkg/metric"
+	metricsstore "k8s.io/kube-state-metrics/pkg/metrics_store"
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	descRuleInfoName   = "policyreport_rule_info"
+	descRuleInfoHelp   = "Content of the Insights rule behind a policy, to be joined with the other PolicyReport metrics on policy."
+	descRuleInfoLabels = []string{"policy", "title", "reboot_required", "kb"}
+)
+
+const (
+	// ruleContentKey is the key of the ConfigMap data holding the rule
+	// content.
+	ruleContentKey = "rule-content.yaml"
+	// ruleContentReloadPeriod is how often the rule content is checked for
+	// changes.
+	ruleContentReloadPeriod = time.Minute
+)
+
+// ruleContent is what is known about an Insights rule, indexed by policy in the
+// rules bundle. Fields other than these are ignored.
+type ruleContent struct {
+	Title          string `json:"title"`
+	Description    string `json:"description,omitempty"`
+	KB             string `json:"kb,omitempty"`
+	RebootRequired bool   `json:"reboot_required,omitempty"`
+}
+
+// ruleInfo is a rule fed to the MetricsStore, named and identified by its
+// policy.
+type ruleInfo struct {
+	metav1.ObjectMeta
+	content ruleContent
+}
+
+// parseRuleContent decodes a YAML or JSON rules bundle mapping policies to
+// their content.
+func parseRuleContent(data []byte) (map[string]ruleContent, error) {
+	rules := map[string]ruleContent{}
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("cannot decode rule content: %w", err)
+	}
+	for policy, content := range rules {
+		if policy == "" {
+			return nil, fmt.Errorf("rule content has an entry without policy")
+		}
+		if content.Title == "" {
+			return nil, fmt.Errorf("rule content of %s has no title", policy)
+		}
+	}
+	return rules, nil
+}
+
+// ruleInfos returns the rules sorted by policy, as objects of the MetricsStore.
+func ruleInfos(rules map[string]ruleContent) []interface{} {
+	policies := make([]string, 0, len(rules))
+	for policy := range rules {
+		policies = append(policies, policy)
+	}
+	sort.Strings(policies)
+	infos := make([]interface{}, 0, len(rules))
+	for _, policy := range policies {
+		infos = append(infos, &ruleInfo{
+			ObjectMeta: metav1.ObjectMeta{Name: policy, UID: types.UID(policy)},
+			content:    rules[policy],
+		})
+	}
+	return infos
+}
+
+func getRuleInfoMetricFamilies() []metric.FamilyGenerator {
+	return []metric.FamilyGenerator{
+		{
+			Name: descRuleInfoName,
+			Type: metric.Gauge,
+			Help: descRuleInfoHelp,
+			GenerateFunc: func(obj interface{}) *metric.Family {
+				rule := obj.(*ruleInfo)
+				return &metric.Family{
+					Metrics: []*metric.Metric{
+						{
+							LabelKeys:   descRuleInfoLabels,
+							LabelValues: []string{rule.Name, rule.content.Title, strconv.FormatBool(rule.content.RebootRequired), rule.content.KB},
+							Value:       1,
+						},
+					},
+				}
+			},
+		},
+	}
+}
+
+// ruleContentLoader keeps a MetricsStore in sync with the rule content read
+// from a file or a ConfigMap.
+type ruleContentLoader struct {
+	client    dynamic.Interface
+	file      string
+	configMap string
+	store     *metricsstore.MetricsStore
+
+	// last is the content last loaded into the store.
+	last []byte
+}
+
+// load replaces the rules of the store if their content changed since the last
+// load. The store is left alone when the content cannot be read or parsed.
+func (l *ruleContentLoader) load() error {
+	data, err := readConfig(l.client, l.file, l.configMap, ruleContentKey)
+	if err != nil {
+		return err
+	}
+	if l.last != nil && bytes.Equal(data, l.last) {
+		return nil
+	}
+	rules, err := parseRuleContent(data)
+	if err != nil {
+		return err
+	}
+	if err := l.store.Replace(ruleInfos(rules), ""); err != nil {
+		return err
+	}
+	klog.Infof("Loaded the content of %d rules", len(rules))
+	l.last = data
+	return nil
+}
+
+// run reloads the rule content every period until the context is done.
+func (l *ruleContentLoader) run(ctx context.Context, period time.Duration) {
+	wait.Until(func() {
+		if err := l.load(); err != nil {
+			klog.Warningf("Error reloading rule content, keeping the previous one: %v", err)
+		}
+	}, period, ctx.Done())
+}
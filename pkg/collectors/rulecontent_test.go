@@ -0,0 +1,147 @@
+// Copyright (c) 2026 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/kube-state-metrics/pkg/metric"
+	metricsstore "k8s.io/kube-state-metrics/pkg/metrics_store"
+)
+
+func Test_parseRuleContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    map[string]ruleContent
+		wantErr bool
+	}{
+		{
+			name: "bundle",
+			data: `
+MASTER_DEFINED_AS_MACHINESET:
+  title: Master nodes are defined as a MachineSet
+  description: Master nodes should not be managed by a MachineSet.
+  kb: https://access.redhat.com/solutions/1
+  reboot_required: true
+  resolution_risk: 2
+`,
+			want: map[string]ruleContent{
+				"MASTER_DEFINED_AS_MACHINESET": {
+					Title:          "Master nodes are defined as a MachineSet",
+					Description:    "Master nodes should not be managed by a MachineSet.",
+					KB:             "https://access.redhat.com/solutions/1",
+					RebootRequired: true,
+				},
+			},
+		}, {
+			name: "missing title",
+			data: `
+MASTER_DEFINED_AS_MACHINESET:
+  kb: https://access.redhat.com/solutions/1
+`,
+			wantErr: true,
+		}, {
+			name:    "not a bundle",
+			data:    `[MASTER_DEFINED_AS_MACHINESET]`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRuleContent([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v got %v", tt.wantErr, err)
+			}
+			if !tt.wantErr && len(got) != len(tt.want) {
+				t.Fatalf("expected %v got %v", tt.want, got)
+			}
+			for policy, content := range tt.want {
+				if got[policy] != content {
+					t.Errorf("%s: expected %+v got %+v", policy, content, got[policy])
+				}
+			}
+		})
+	}
+}
+
+func Test_ruleContentLoader(t *testing.T) {
+	families := getRuleInfoMetricFamilies()
+	newStore := func() *metricsstore.MetricsStore {
+		return metricsstore.NewMetricsStore(
+			metric.ExtractMetricFamilyHeaders(families),
+			metric.ComposeMetricGenFuncs(families),
+		)
+	}
+	machineSet := `policyreport_rule_info{policy="MASTER_DEFINED_AS_MACHINESET",title="Master nodes are defined as a MachineSet",reboot_required="false",kb="https://access.redhat.com/solutions/1"} 1`
+	nodes := `policyreport_rule_info{policy="NODES_MINIMUM_REQUIREMENTS_NOT_MET",title="Nodes do not meet the minimum requirements",reboot_required="true",kb=""} 1`
+
+	t.Run("file", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "rule-content.yaml")
+		// Files are replaced as a whole, as in a mounted ConfigMap, so that
+		// the loader never reads one half written.
+		write := func(data string) {
+			if err := os.WriteFile(file+".tmp", []byte(data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Rename(file+".tmp", file); err != nil {
+				t.Fatal(err)
+			}
+		}
+		write(`
+MASTER_DEFINED_AS_MACHINESET:
+  title: Master nodes are defined as a MachineSet
+  kb: https://access.redhat.com/solutions/1
+`)
+		store := newStore()
+		loader := &ruleContentLoader{file: file, store: store}
+		if err := loader.load(); err != nil {
+			t.Fatal(err)
+		}
+		waitForMetrics(t, store, machineSet, true)
+
+		ctx, cancel := context.WithCancel(context.TODO())
+		defer cancel()
+		go loader.run(ctx, 10*time.Millisecond)
+
+		// A change of the file replaces the rules.
+		write(`
+NODES_MINIMUM_REQUIREMENTS_NOT_MET:
+  title: Nodes do not meet the minimum requirements
+  reboot_required: true
+`)
+		waitForMetrics(t, store, nodes, true)
+		waitForMetrics(t, store, machineSet, false)
+
+		// Invalid content keeps the previous rules.
+		write(`NODES_MINIMUM_REQUIREMENTS_NOT_MET: {}`)
+		time.Sleep(50 * time.Millisecond)
+		waitForMetrics(t, store, nodes, true)
+	})
+
+	t.Run("ConfigMap", func(t *testing.T) {
+		client := fake.NewSimpleDynamicClient(scheme.Scheme, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "rule-content", Namespace: "open-cluster-management"},
+			Data: map[string]string{ruleContentKey: `
+MASTER_DEFINED_AS_MACHINESET:
+  title: Master nodes are defined as a MachineSet
+  kb: https://access.redhat.com/solutions/1
+`},
+		})
+		store := newStore()
+		loader := &ruleContentLoader{client: client, configMap: "open-cluster-management/rule-content", store: store}
+		if err := loader.load(); err != nil {
+			t.Fatal(err)
+		}
+		waitForMetrics(t, store, machineSet, true)
+	})
+}
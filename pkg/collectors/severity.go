@@ -0,0 +1,104 @@
+// Copyright (c) 2026 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/yaml"
+)
+
+// severityMappingKey is the key of the ConfigMap data holding a severity
+// mapping.
+const severityMappingKey = "severity-mapping.yaml"
+
+// severityMapping tells how the severity label of a PolicyReport result is
+// derived from the result. The severity is looked up in Values by the value of
+// the result property named Property, then taken from the severity of the
+// result itself when FallbackToResultSeverity is set, and is Default otherwise.
+type severityMapping struct {
+	Property                 string            `json:"property,omitempty"`
+	Values                   map[string]string `json:"values,omitempty"`
+	FallbackToResultSeverity bool              `json:"fallbackToResultSeverity,omitempty"`
+	Default                  string            `json:"default,omitempty"`
+}
+
+// defaultSeverityMapping maps the Insights total_risk of a result onto the
+// low/moderate/important/critical vocabulary.
+var defaultSeverityMapping = severityMapping{
+	Property: "total_risk",
+	Values: map[string]string{
+		"1": "low",
+		"2": "moderate",
+		"3": "important",
+		"4": "critical",
+	},
+	Default: "unknown",
+}
+
+// parseSeverityMapping decodes and validates a YAML or JSON severity mapping.
+// An unset default is "unknown".
+func parseSeverityMapping(data []byte) (severityMapping, error) {
+	m := severityMapping{}
+	if err := yaml.UnmarshalStrict(data, &m); err != nil {
+		return severityMapping{}, fmt.Errorf("cannot decode severity mapping: %w", err)
+	}
+	if m.Default == "" {
+		m.Default = defaultSeverityMapping.Default
+	}
+	if err := m.validate(); err != nil {
+		return severityMapping{}, err
+	}
+	return m, nil
+}
+
+// validate reports a mapping that cannot map any result, or that maps results
+// to empty severities.
+func (m severityMapping) validate() error {
+	if m.Property == "" && !m.FallbackToResultSeverity {
+		return errors.New("severity mapping needs a property, fallbackToResultSeverity or both")
+	}
+	if m.Property == "" && len(m.Values) > 0 {
+		return errors.New("severity mapping has values but no property")
+	}
+	if m.Property != "" && len(m.Values) == 0 {
+		return fmt.Errorf("severity mapping of property %s has no values", m.Property)
+	}
+	for value, severity := range m.Values {
+		if strings.TrimSpace(severity) == "" {
+			return fmt.Errorf("severity mapping of %s=%q is empty", m.Property, value)
+		}
+	}
+	if strings.TrimSpace(m.Default) == "" {
+		return errors.New("severity mapping default is empty")
+	}
+	return nil
+}
+
+// severity returns the severity of the result.
+func (m severityMapping) severity(r reportResult) string {
+	if m.Property != "" {
+		if severity, ok := m.Values[r.properties[m.Property]]; ok {
+			return severity
+		}
+	}
+	if m.FallbackToResultSeverity && r.severity != "" {
+		return r.severity
+	}
+	return m.Default
+}
+
+// loadSeverityMapping reads the severity mapping from a file or from the
+// severityMappingKey of a ConfigMap given as namespace/name, through the given
+// client. It returns defaultSeverityMapping when neither is given.
+func loadSeverityMapping(client dynamic.Interface, file string, configMap string) (severityMapping, error) {
+	data, err := readConfig(client, file, configMap, severityMappingKey)
+	if err != nil || data == nil {
+		return defaultSeverityMapping, err
+	}
+	return parseSeverityMapping(data)
+}
@@ -0,0 +1,212 @@
+// Copyright (c) 2026 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+func Test_parseSeverityMapping(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    severityMapping
+		wantErr bool
+	}{
+		{
+			name: "property and fallback",
+			data: `
+property: risk_of_change
+values:
+  "1": low
+  "2": high
+fallbackToResultSeverity: true
+`,
+			want: severityMapping{
+				Property:                 "risk_of_change",
+				Values:                   map[string]string{"1": "low", "2": "high"},
+				FallbackToResultSeverity: true,
+				Default:                  "unknown",
+			},
+		}, {
+			name: "result severity only",
+			data: `
+fallbackToResultSeverity: true
+default: none
+`,
+			want: severityMapping{
+				FallbackToResultSeverity: true,
+				Default:                  "none",
+			},
+		}, {
+			name:    "nothing to map",
+			data:    `default: unknown`,
+			wantErr: true,
+		}, {
+			name:    "property without values",
+			data:    `property: total_risk`,
+			wantErr: true,
+		}, {
+			name: "values without property",
+			data: `
+fallbackToResultSeverity: true
+values:
+  "1": low
+`,
+			wantErr: true,
+		}, {
+			name: "empty severity",
+			data: `
+property: total_risk
+values:
+  "1": ""
+`,
+			wantErr: true,
+		}, {
+			name:    "unknown field",
+			data:    `propertyName: total_risk`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSeverityMapping([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v got %v", tt.wantErr, err)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v got %+v", tt.want, got)
+			}
+		})
+	}
+
+	if err := defaultSeverityMapping.validate(); err != nil {
+		t.Errorf("expected the default mapping to be valid: %v", err)
+	}
+}
+
+func Test_severityMapping_severity(t *testing.T) {
+	fallback := severityMapping{
+		Property:                 "total_risk",
+		Values:                   defaultSeverityMapping.Values,
+		FallbackToResultSeverity: true,
+		Default:                  "unknown",
+	}
+
+	tests := []struct {
+		name    string
+		mapping severityMapping
+		result  reportResult
+		want    string
+	}{
+		{
+			name:    "default mapping",
+			mapping: defaultSeverityMapping,
+			result:  reportResult{properties: map[string]string{"total_risk": "4"}},
+			want:    "critical",
+		}, {
+			name:    "default mapping ignores the result severity",
+			mapping: defaultSeverityMapping,
+			result:  reportResult{severity: "high"},
+			want:    "unknown",
+		}, {
+			name:    "property first",
+			mapping: fallback,
+			result:  reportResult{severity: "high", properties: map[string]string{"total_risk": "1"}},
+			want:    "low",
+		}, {
+			name:    "fallback to the result severity",
+			mapping: fallback,
+			result:  reportResult{severity: "high", properties: map[string]string{"total_risk": "9"}},
+			want:    "high",
+		}, {
+			name:    "nothing matches",
+			mapping: fallback,
+			result:  reportResult{},
+			want:    "unknown",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.mapping.severity(tt.result); got != tt.want {
+				t.Errorf("expected %s got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_loadSeverityMapping(t *testing.T) {
+	data := "fallbackToResultSeverity: true\n"
+	want := severityMapping{FallbackToResultSeverity: true, Default: "unknown"}
+
+	file := filepath.Join(t.TempDir(), "severity-mapping.yaml")
+	if err := os.WriteFile(file, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	client := fake.NewSimpleDynamicClient(scheme.Scheme,
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "severity", Namespace: "open-cluster-management"},
+			Data:       map[string]string{severityMappingKey: data},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "empty", Namespace: "open-cluster-management"},
+		})
+
+	tests := []struct {
+		name      string
+		file      string
+		configMap string
+		want      severityMapping
+		wantErr   bool
+	}{
+		{
+			name: "default",
+			want: defaultSeverityMapping,
+		}, {
+			name: "file",
+			file: file,
+			want: want,
+		}, {
+			name:      "ConfigMap",
+			configMap: "open-cluster-management/severity",
+			want:      want,
+		}, {
+			name:      "file and ConfigMap",
+			file:      file,
+			configMap: "open-cluster-management/severity",
+			wantErr:   true,
+		}, {
+			name:    "missing file",
+			file:    filepath.Join(t.TempDir(), "missing.yaml"),
+			wantErr: true,
+		}, {
+			name:      "ConfigMap without mapping",
+			configMap: "open-cluster-management/empty",
+			wantErr:   true,
+		}, {
+			name:      "ConfigMap without namespace",
+			configMap: "severity",
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadSeverityMapping(client, tt.file, tt.configMap)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v got %v", tt.wantErr, err)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v got %+v", tt.want, got)
+			}
+		})
+	}
+}
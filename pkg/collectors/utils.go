@@ -4,16 +4,11 @@
 package collectors
 
 import (
-	"context"
+	"regexp"
+	"time"
 
-	clusterv1 "open-cluster-management.io/api/cluster/v1"
-	ocinfrav1 "github.com/openshift/api/config/v1"
 	"github.com/prometheus/client_golang/prometheus"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
-	"k8s.io/client-go/dynamic"
-	"k8s.io/klog/v2"
 )
 
 var (
@@ -33,6 +28,14 @@ var (
 		[]string{"resource"},
 	)
 
+	CollectorReadyMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "collector_ready",
+			Help: "Whether the CustomResourceDefinition of a collector is established and its reflectors are running",
+		},
+		[]string{"collector"},
+	)
+
 	cvGVR = schema.GroupVersionResource{
 		Group:    "config.openshift.io",
 		Version:  "v1",
@@ -44,41 +47,26 @@ var (
 		Version:  "v1",
 		Resource: "managedclusters",
 	}
-)
 
-func getClusterID(c dynamic.Interface, clusterName string) string {
-	clusterId := ""
-	if clusterName == "local-cluster" {
-		cvObj, errCv := c.Resource(cvGVR).Get(context.TODO(), "version", metav1.GetOptions{})
-		if errCv != nil {
-			klog.Warningf("Error getting cluster version %v \n", errCv)
-			return clusterId
-		}
-		cv := &ocinfrav1.ClusterVersion{}
-		err := runtime.DefaultUnstructuredConverter.FromUnstructured(cvObj.UnstructuredContent(), &cv)
-		if err != nil {
-			klog.Warningf("Error unmarshal cluster version object%v \n", err)
-			return clusterId
-		}
-		return string(cv.Spec.ClusterID)
-	} else {
-		mcObj, errMc := c.Resource(mcGVR).Get(context.TODO(), clusterName, metav1.GetOptions{})
-		if errMc != nil {
-			klog.Warningf("Error getting ManagedCluster %v \n", errMc)
-			return clusterId
-		}
-		mc := &clusterv1.ManagedCluster{}
-		err := runtime.DefaultUnstructuredConverter.FromUnstructured(mcObj.UnstructuredContent(), &mc)
-		if err != nil {
-			klog.Warningf("Error unmarshal ManagedCluster object%v \n", err)
-			return clusterId
-		}
-		for _, claimInfo := range mc.Status.ClusterClaims {
-			if claimInfo.Name == "id.openshift.io" {
-				return string(claimInfo.Value)
-			}
-		}
+	crdGVR = schema.GroupVersionResource{
+		Group:    "apiextensions.k8s.io",
+		Version:  "v1",
+		Resource: "customresourcedefinitions",
 	}
-	return clusterId
 
+	cmGVR = schema.GroupVersionResource{
+		Version:  "v1",
+		Resource: "configmaps",
+	}
+)
+
+// now is the clock of the collectors, replaced in tests.
+var now = time.Now
+
+var invalidLabelCharRE = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// sanitizeLabelName turns a Kubernetes label key into a valid Prometheus label
+// name, prefixed with "label_" as in the kube-state-metrics *_labels families.
+func sanitizeLabelName(s string) string {
+	return "label_" + invalidLabelCharRE.ReplaceAllString(s, "_")
 }
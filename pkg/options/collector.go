@@ -9,11 +9,13 @@ func init() {
 	//TODO this is because the CollectorSet struct is validate the collectors from the commandline using
 	//"DefaultCollectors". https://github.com/kubernetes/kube-state-metrics/blob/master/pkg/options/types.go#L80
 	koptions.DefaultCollectors["policyreports"] = struct{}{}
+	koptions.DefaultCollectors["clusterpolicyreports"] = struct{}{}
 }
 
 var (
 	DefaultNamespaces = koptions.NamespaceList{metav1.NamespaceAll}
 	DefaultCollectors = koptions.CollectorSet{
-		"policyreports": struct{}{},
+		"policyreports":        struct{}{},
+		"clusterpolicyreports": struct{}{},
 	}
 )
@@ -4,26 +4,48 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/stolostron/insights-metrics/pkg/collectors"
 	"k8s.io/klog/v2"
 	koptions "k8s.io/kube-state-metrics/pkg/options"
 )
 
 type Options struct {
-	Apiserver       string
-	Kubeconfig      string
-	Help            bool
-	Port            int
-	Host            string
-	TelemetryPort   int
-	TelemetryHost   string
-	TLSCrtFile      string
-	TLSKeyFile      string
-	Collectors      koptions.CollectorSet
-	Namespaces      koptions.NamespaceList
-	MetricBlacklist koptions.MetricSet
-	MetricWhitelist koptions.MetricSet
-	Version         bool
+	Apiserver         string
+	Kubeconfig        string
+	Help              bool
+	Port              int
+	Host              string
+	TelemetryPort     int
+	TelemetryHost     string
+	TLSCrtFile        string
+	TLSKeyFile        string
+	Collectors        koptions.CollectorSet
+	Namespaces        koptions.NamespaceList
+	NamespaceDenylist koptions.NamespaceList
+	MetricBlacklist   koptions.MetricSet
+	MetricWhitelist   koptions.MetricSet
+	Version           bool
+
+	LocalClusterName string
+	ClusterIDSources StringList
+
+	ManagedClusterLabelsAllowlist StringList
+	MaxReportAge                  time.Duration
+	MaxResultResources            int
+	MissingReportGracePeriod      time.Duration
+	PolicyReportAPIVersion        string
+	OnlyManagedClusterNamespaces  bool
+	PolicyReportLabelSelector     string
+	PolicyReportFieldSelector     string
+	PolicyReportSources           StringList
+	SeverityMappingFile           string
+	SeverityMappingConfigMap      string
+	RuleContentFile               string
+	RuleContentConfigMap          string
+	FindingStateFile              string
 
 	EnableGZIPEncoding bool
 }
@@ -59,9 +81,26 @@ func (o *Options) AddFlags() {
 	flag.StringVar(&o.TLSCrtFile, "tls-crt-file", "", `TLS certificate file path.`)
 	flag.StringVar(&o.TLSKeyFile, "tls-key-file", "", `TLS key file path.`)
 	flag.Var(&o.Collectors, "collectors", fmt.Sprintf("Comma-separated list of collectors to be enabled. Defaults to %q", &DefaultCollectors))
-	flag.Var(&o.Namespaces, "namespace", fmt.Sprintf("Comma-separated list of namespaces to be enabled. Defaults to %q", &DefaultNamespaces))
+	flag.Var(&o.Namespaces, "namespace", fmt.Sprintf("Comma-separated list of namespaces to be enabled. Entries made of namespace name characters and * are globs, where * matches any run of characters. Other entries that are not valid namespace names are regular expressions matching whole namespace names. Defaults to %q", &DefaultNamespaces))
+	flag.Var(&o.NamespaceDenylist, "namespace-denylist", "Comma-separated list of namespaces never to be enabled, such as open-cluster-management-*. Entries made of namespace name characters and * are globs, where * matches any run of characters. Other entries that are not valid namespace names are regular expressions matching whole namespace names.")
+	flag.BoolVar(&o.OnlyManagedClusterNamespaces, "only-managed-cluster-namespaces", false, "Only collect the namespaces named after a ManagedCluster, among those enabled, watching namespaces as ManagedClusters are imported or detached.")
 	flag.Var(&o.MetricWhitelist, "metric-whitelist", "Comma-separated list of metrics to be exposed. The whitelist and blacklist are mutually exclusive.")
 	flag.Var(&o.MetricBlacklist, "metric-blacklist", "Comma-separated list of metrics not to be enabled. The whitelist and blacklist are mutually exclusive.")
+	flag.StringVar(&o.LocalClusterName, "local-cluster-name", "", "Name of the ManagedCluster representing the hub. Defaults to the ManagedCluster labelled local-cluster=true.")
+	flag.Var(&o.ClusterIDSources, "cluster-id-sources", fmt.Sprintf("Comma-separated list of sources tried in order to resolve a managed cluster ID: ClusterClaim names, uid or name. Defaults to %q", strings.Join(collectors.DefaultClusterIDSources, ",")))
+	flag.Var(&o.ManagedClusterLabelsAllowlist, "managedcluster-labels-allowlist", "Comma-separated list of ManagedCluster labels added to policyreport_info as label_<sanitized name>.")
+	flag.DurationVar(&o.MaxReportAge, "max-report-age", 0, "Age after which a PolicyReport is flagged by policyreport_stale. Zero disables policyreport_stale.")
+	flag.IntVar(&o.MaxResultResources, "max-result-resources", 0, "Maximum number of policyreport_result_resources series, one per resource affected by a policy, per managed cluster. Zero disables policyreport_result_resources.")
+	flag.DurationVar(&o.MissingReportGracePeriod, "missing-report-grace-period", 24*time.Hour, "Time an available ManagedCluster can go without a PolicyReport before policyreport_missing flags it. Zero disables policyreport_missing.")
+	flag.StringVar(&o.PolicyReportAPIVersion, "policyreport-api-version", "", "Group/version of the PolicyReport API to read exclusively, one of wgpolicyk8s.io/v1beta1, wgpolicyk8s.io/v1alpha2 or openreports.io/v1alpha1. Defaults to reading both wgpolicyk8s.io and openreports.io while their CustomResourceDefinition is established, each on its most preferred served version.")
+	flag.StringVar(&o.PolicyReportLabelSelector, "policyreport-label-selector", "", "Label selector restricting the PolicyReports and ClusterPolicyReports listed and watched.")
+	flag.StringVar(&o.PolicyReportFieldSelector, "policyreport-field-selector", "", "Field selector restricting the PolicyReports and ClusterPolicyReports listed and watched.")
+	flag.Var(&o.PolicyReportSources, "policyreport-sources", "Comma-separated list of the sources, such as insights or kyverno, whose PolicyReport results are collected. Reports carrying only a summary, which names no source, are then ignored. Defaults to every source.")
+	flag.StringVar(&o.SeverityMappingFile, "severity-mapping-file", "", "Path of the YAML file mapping PolicyReport results to their severity. Defaults to the Insights total_risk mapping.")
+	flag.StringVar(&o.SeverityMappingConfigMap, "severity-mapping-configmap", "", "ConfigMap, as namespace/name, whose severity-mapping.yaml key maps PolicyReport results to their severity. Mutually exclusive with --severity-mapping-file.")
+	flag.StringVar(&o.RuleContentFile, "rule-content-file", "", "Path of the YAML rules bundle mapping policies to their title, description, kb link and reboot_required, exposed by policyreport_rule_info and reloaded when it changes.")
+	flag.StringVar(&o.RuleContentConfigMap, "rule-content-configmap", "", "ConfigMap, as namespace/name, whose rule-content.yaml key holds the rules bundle exposed by policyreport_rule_info. Mutually exclusive with --rule-content-file.")
+	flag.StringVar(&o.FindingStateFile, "finding-state-file", "", "Path of the file the first-seen times of the PolicyReport findings are persisted to, so that policyreport_finding_first_seen_timestamp_seconds and policyreport_finding_resolution_seconds survive restarts. Kept in memory only when empty.")
 	flag.BoolVar(&o.EnableGZIPEncoding, "enable-gzip-encoding", false, "Gzip responses when requested by clients via 'Accept-Encoding: gzip' header.")
 }
 
@@ -0,0 +1,32 @@
+// Copyright (c) 2026 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package options
+
+import (
+	"strings"
+)
+
+// StringList represents an ordered list of values given as a comma-separated
+// flag.
+type StringList []string
+
+func (l *StringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+// Set converts a comma-separated string into a slice and appends it to the StringList.
+func (l *StringList) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		v = strings.TrimSpace(v)
+		if len(v) != 0 {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+// Type returns a descriptive string about the StringList type.
+func (l *StringList) Type() string {
+	return "string"
+}